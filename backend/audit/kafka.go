@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"time"
 
 	"backend/config"
 	"backend/models"
@@ -12,7 +13,8 @@ import (
 )
 
 type Service struct {
-	writer *kafka.Writer
+	writer      *kafka.Writer
+	eventWriter *kafka.Writer
 }
 
 func New(cfg *config.Config) *Service {
@@ -21,8 +23,13 @@ func New(cfg *config.Config) *Service {
 		Topic:    "qc-audit-logs",
 		Balancer: &kafka.LeastBytes{},
 	}
+	eventWriter := &kafka.Writer{
+		Addr:     kafka.TCP(cfg.KafkaURL),
+		Topic:    "qc-audit-events",
+		Balancer: &kafka.LeastBytes{},
+	}
 	log.Println("Kafka producer initialized")
-	return &Service{writer: writer}
+	return &Service{writer: writer, eventWriter: eventWriter}
 }
 
 func (s *Service) ProduceAuditLog(workflowLog models.WorkflowLog) {
@@ -44,8 +51,34 @@ func (s *Service) ProduceAuditLog(workflowLog models.WorkflowLog) {
 	}()
 }
 
+// ProduceAuditEvent records administrative actions on their own topic, separate from workflow transitions
+func (s *Service) ProduceAuditEvent(event models.AuditEvent) {
+	go func() {
+		if event.Timestamp.IsZero() {
+			event.Timestamp = time.Now()
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("Failed to marshal audit event: %v", err)
+			return
+		}
+
+		err = s.eventWriter.WriteMessages(context.Background(),
+			kafka.Message{
+				Value: data,
+			},
+		)
+		if err != nil {
+			log.Printf("Failed to write to Kafka: %v", err)
+		}
+	}()
+}
+
 func (s *Service) Shutdown() {
 	if s.writer != nil {
 		s.writer.Close()
 	}
+	if s.eventWriter != nil {
+		s.eventWriter.Close()
+	}
 }
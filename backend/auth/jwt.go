@@ -1,43 +1,59 @@
 /*
 Here I am using jwt for the authentication.
-The refresh token mechanism is not implemented yet for the sake of simplicity.
+Signing keys come from config (see InitKeys). Access tokens are short-lived; clients renew them with the refresh tokens
+issued by services.Tokens (POST /auth/refresh).
 */
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte("super-secret-key") // Should be loaded from config
+// AccessTokenTTL is how long an access token stays valid; set from config at startup
+var AccessTokenTTL = 15 * time.Minute
+
+func init() {
+	// iat is compared with the per-user revocation time, so a login right after a
+	// revocation must not fall into the same second
+	jwt.TimePrecision = time.Millisecond
+}
 
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Role   string `json:"role"`
+	UserID         uint   `json:"user_id"`
+	OrganizationID uint   `json:"org_id"`
+	Role           string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uint, role string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+func GenerateToken(userID uint, organizationID uint, role string) (string, error) {
+	expirationTime := time.Now().Add(AccessTokenTTL)
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
 	claims := &Claims{
-		UserID: userID,
-		Role:   role,
+		UserID:         userID,
+		OrganizationID: organizationID,
+		Role:           role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(jti), // Lets a single token be revoked
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	return keys.sign(claims)
 }
 
 func ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// legacyKeyID is the kid given to the HMAC secret; tokens without a kid are verified with it
+const legacyKeyID = "default"
+
+// insecureSecret is the config fallback for JWT_SECRET
+const insecureSecret = "super-secret-key"
+
+// signingKey is one entry of the key set. Keys loaded from a public key file
+// can only verify tokens, which is how a retired key stays valid until its
+// tokens expire.
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet holds every key that may verify tokens and the one that signs new ones
+type KeySet struct {
+	keys   map[string]*signingKey
+	active *signingKey
+}
+
+// keys is replaced by InitKeys at startup
+var keys = hmacKeySet(insecureSecret)
+
+func hmacKeySet(secret string) *KeySet {
+	key := hmacKey(secret)
+	return &KeySet{keys: map[string]*signingKey{key.id: key}, active: key}
+}
+
+func hmacKey(secret string) *signingKey {
+	return &signingKey{id: legacyKeyID, method: jwt.SigningMethodHS256, signKey: []byte(secret), verifyKey: []byte(secret)}
+}
+
+// InitKeys loads the signing keys. keyFiles is a comma-separated list of
+// kid=path entries pointing at PEM files: RSA keys sign with RS256, Ed25519
+// keys with EdDSA. activeKeyID picks the key that signs (default: the first
+// file). When no files are given, tokens are signed with the HS256 secret.
+// A configured secret stays valid for verification alongside key files, so
+// tokens issued before a switch to asymmetric keys keep working until they
+// expire; the built-in default secret is dropped as soon as key files exist.
+func InitKeys(secret string, keyFiles string, activeKeyID string) error {
+	set := &KeySet{keys: map[string]*signingKey{}}
+	if secret != "" && (secret != insecureSecret || strings.TrimSpace(keyFiles) == "") {
+		set.keys[legacyKeyID] = hmacKey(secret)
+	}
+
+	var first string
+	for _, entry := range strings.Split(keyFiles, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, path, ok := strings.Cut(entry, "=")
+		if !ok || id == "" || path == "" {
+			return fmt.Errorf("invalid key file entry %q, expected kid=path", entry)
+		}
+		key, err := loadKeyFile(id, path)
+		if err != nil {
+			return err
+		}
+		set.keys[id] = key
+		if first == "" {
+			first = id
+		}
+	}
+
+	switch {
+	case activeKeyID != "":
+	case first != "":
+		activeKeyID = first
+	default:
+		activeKeyID = legacyKeyID
+	}
+	active, ok := set.keys[activeKeyID]
+	if !ok {
+		return fmt.Errorf("active signing key %q is not configured", activeKeyID)
+	}
+	if active.signKey == nil {
+		return fmt.Errorf("active signing key %q has no private key", activeKeyID)
+	}
+	set.active = active
+
+	if active.id == legacyKeyID && secret == insecureSecret {
+		log.Println("WARNING: signing tokens with the default JWT secret; set JWT_SECRET or JWT_KEY_FILES")
+	}
+
+	keys = set
+	return nil
+}
+
+func loadKeyFile(id string, path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key %q: %w", id, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q: no PEM block in %s", id, path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %q: unsupported PEM block %q", id, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", id, err)
+	}
+
+	key := &signingKey{id: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.signKey, key.verifyKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.verifyKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.signKey, key.verifyKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.verifyKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("key %q: only RSA and Ed25519 keys are supported", id)
+	}
+	return key, nil
+}
+
+func (s *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.active.method, claims)
+	token.Header["kid"] = s.active.id
+	return token.SignedString(s.active.signKey)
+}
+
+// keyFunc picks the verification key by kid and refuses any other algorithm than the key's own
+func (s *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+	if id == "" {
+		id = legacyKeyID
+	}
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.verifyKey, nil
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKS returns the public halves of the asymmetric keys. HMAC secrets are never published.
+func JWKS() []JWK {
+	set := []JWK{}
+	for _, key := range keys.keys {
+		switch k := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set = append(set, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.id,
+				Use:       "sig",
+				Algorithm: key.method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set = append(set, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.id,
+				Use:       "sig",
+				Algorithm: key.method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(k),
+			})
+		}
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i].KeyID < set[j].KeyID })
+	return set
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPIssuer is the issuer of LDAP identities. It does not include the server
+// address, so users keep their accounts when the directory moves.
+const LDAPIssuer = "ldap"
+
+// ErrLDAPInvalidCredentials means the directory has no such user or the password is wrong
+var ErrLDAPInvalidCredentials = errors.New("invalid LDAP credentials")
+
+type LDAPConfig struct {
+	URL          string // ldap://host:389 or ldaps://host:636
+	StartTLS     bool
+	BindDN       string // Service account used to look users up
+	BindPassword string
+	BaseDN       string
+	UserFilter   string // %s is replaced with the escaped username, e.g. (sAMAccountName=%s)
+	GroupFilter  string // Optional; %s is replaced with the user's escaped DN, e.g. (member=%s)
+	IDAttribute  string // Stable identifier: entryUUID (OpenLDAP) or objectGUID (AD)
+	EmailAttr    string
+	GroupAttr    string // Group DNs on the user entry, memberOf in AD
+}
+
+// LDAPDirectory checks passwords by binding as the user and reads who they are.
+// Every login uses its own connection.
+type LDAPDirectory struct {
+	cfg LDAPConfig
+}
+
+func NewLDAPDirectory(cfg LDAPConfig) *LDAPDirectory {
+	return &LDAPDirectory{cfg: cfg}
+}
+
+// Authenticate finds the user with the service account, binds as them with the given
+// password and returns their identity. Groups are listed both as full DN and as CN.
+func (d *LDAPDirectory) Authenticate(username string, password string) (*Identity, error) {
+	if username == "" || password == "" {
+		// An empty password would be an unauthenticated bind, which many servers accept
+		return nil, ErrLDAPInvalidCredentials
+	}
+
+	conn, err := ldap.DialURL(d.cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}))
+	if err != nil {
+		return nil, fmt.Errorf("ldap dial: %w", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(10 * time.Second)
+
+	if d.cfg.StartTLS {
+		parsed, err := url.Parse(d.cfg.URL)
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: parsed.Hostname()}); err != nil {
+			return nil, fmt.Errorf("ldap starttls: %w", err)
+		}
+	}
+	if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+		return nil, fmt.Errorf("ldap service bind: %w", err)
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		d.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(d.cfg.UserFilter, ldap.EscapeFilter(username)),
+		[]string{d.cfg.IDAttribute, d.cfg.EmailAttr, d.cfg.GroupAttr},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("ldap user search: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrLDAPInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrLDAPInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap user bind: %w", err)
+	}
+
+	id := entry.GetRawAttributeValue(d.cfg.IDAttribute)
+	if len(id) == 0 {
+		return nil, fmt.Errorf("ldap entry %s has no %s", entry.DN, d.cfg.IDAttribute)
+	}
+
+	groups := entry.GetAttributeValues(d.cfg.GroupAttr)
+	if d.cfg.GroupFilter != "" {
+		// Search groups as the service account; the user may not be allowed to
+		if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap service bind: %w", err)
+		}
+		result, err := conn.Search(ldap.NewSearchRequest(
+			d.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+			fmt.Sprintf(d.cfg.GroupFilter, ldap.EscapeFilter(entry.DN)),
+			[]string{"cn"},
+			nil,
+		))
+		if err != nil {
+			return nil, fmt.Errorf("ldap group search: %w", err)
+		}
+		for _, group := range result.Entries {
+			groups = append(groups, group.DN)
+		}
+	}
+
+	return &Identity{
+		Issuer:   LDAPIssuer,
+		Subject:  ldapID(id),
+		Username: username,
+		Email:    entry.GetAttributeValue(d.cfg.EmailAttr),
+		Groups:   withCommonNames(groups),
+	}, nil
+}
+
+// ldapID keeps text identifiers (entryUUID) as they are and hex-encodes binary ones (objectGUID)
+func ldapID(raw []byte) string {
+	if utf8.Valid(raw) {
+		return string(raw)
+	}
+	return hex.EncodeToString(raw)
+}
+
+// withCommonNames adds the CN of every group DN, so mappings can name groups either way
+func withCommonNames(dns []string) []string {
+	groups := make([]string, 0, 2*len(dns))
+	for _, dn := range dns {
+		groups = append(groups, dn)
+		parsed, err := ldap.ParseDN(dn)
+		if err != nil || len(parsed.RDNs) == 0 {
+			continue
+		}
+		for _, attr := range parsed.RDNs[0].Attributes {
+			if strings.EqualFold(attr.Type, "cn") {
+				groups = append(groups, attr.Value)
+			}
+		}
+	}
+	return groups
+}
@@ -0,0 +1,204 @@
+//go:build integration
+
+package auth_test
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+
+	"backend/auth"
+	"backend/models"
+	"backend/repositories"
+	"backend/services"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+)
+
+// These tests run against the directory in testdata/ldap:
+//
+//	docker compose -f auth/testdata/ldap/compose.yml up -d
+//	go test -tags integration ./auth/
+//
+// LDAP_TEST_URL overrides where it listens.
+
+const (
+	ldapAdminDN       = "cn=admin,dc=qc,dc=local"
+	ldapAdminPassword = "admin"
+	ldapLeadsDN       = "cn=qc-leads,ou=groups,dc=qc,dc=local"
+	ldapAliceDN       = "uid=alice,ou=people,dc=qc,dc=local"
+)
+
+func ldapURL() string {
+	if url := os.Getenv("LDAP_TEST_URL"); url != "" {
+		return url
+	}
+	return "ldap://localhost:3389"
+}
+
+func newTestDirectory() *auth.LDAPDirectory {
+	return auth.NewLDAPDirectory(auth.LDAPConfig{
+		URL:          ldapURL(),
+		BindDN:       ldapAdminDN,
+		BindPassword: ldapAdminPassword,
+		BaseDN:       "dc=qc,dc=local",
+		UserFilter:   "(uid=%s)",
+		GroupFilter:  "(&(objectClass=groupOfNames)(member=%s))",
+		IDAttribute:  "entryUUID",
+		EmailAttr:    "mail",
+		GroupAttr:    "memberOf",
+	})
+}
+
+// modifyLeads adds or removes alice from qc-leads as the directory admin
+func modifyLeads(t *testing.T, add bool) {
+	conn, err := ldap.DialURL(ldapURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.Bind(ldapAdminDN, ldapAdminPassword); err != nil {
+		t.Fatal(err)
+	}
+	request := ldap.NewModifyRequest(ldapLeadsDN, nil)
+	if add {
+		request.Add("member", []string{ldapAliceDN})
+	} else {
+		request.Delete("member", []string{ldapAliceDN})
+	}
+	if err := conn.Modify(request); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLDAPAuthenticateBindsAsTheUser(t *testing.T) {
+	directory := newTestDirectory()
+
+	identity, err := directory.Authenticate("alice", "alice-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Issuer != auth.LDAPIssuer || identity.Subject == "" || identity.Username != "alice" || identity.Email != "alice@qc.local" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+
+	for _, login := range [][2]string{{"alice", "wrong-password"}, {"nobody", "alice-password"}, {"alice", ""}} {
+		if _, err := directory.Authenticate(login[0], login[1]); err != auth.ErrLDAPInvalidCredentials {
+			t.Fatalf("login %q with %q: got %v, want %v", login[0], login[1], err, auth.ErrLDAPInvalidCredentials)
+		}
+	}
+}
+
+func TestLDAPAuthenticateFindsGroups(t *testing.T) {
+	directory := newTestDirectory()
+
+	alice, err := directory.Authenticate("alice", "alice-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, group := range []string{"qc-leads", ldapLeadsDN, "drafters"} {
+		if !slices.Contains(alice.Groups, group) {
+			t.Fatalf("alice's groups %v lack %q", alice.Groups, group)
+		}
+	}
+
+	bob, err := directory.Authenticate("bob", "bob-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(bob.Groups, "drafters") || slices.Contains(bob.Groups, "qc-leads") {
+		t.Fatalf("unexpected groups for bob %v", bob.Groups)
+	}
+}
+
+type nopAuditor struct{}
+
+func (nopAuditor) ProduceAuditLog(models.WorkflowLog)  {}
+func (nopAuditor) ProduceAuditEvent(models.AuditEvent) {}
+
+type nopBroadcaster struct{}
+
+func (nopBroadcaster) BroadcastEvent(uint, string, interface{}) {}
+
+func TestLDAPLoginSyncsRoleAndMemberships(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Project{}, &models.ProjectMember{}, &models.Drawing{}, &models.WorkflowLog{}); err != nil {
+		t.Fatal(err)
+	}
+	leads := &models.Project{OrganizationID: 1, Name: "leads"}
+	drafting := &models.Project{OrganizationID: 1, Name: "drafting"}
+	db.Create(leads)
+	db.Create(drafting)
+
+	mapping, err := services.ParseGroupMapping(
+		"qc-leads=shift_lead",
+		fmt.Sprintf("qc-leads=%d:shift_lead:owner,drafters=%d:drafter", leads.ID, drafting.ID),
+		"drafter",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := services.NewLDAPAuthenticator(newTestDirectory(), repositories.NewProjectRepository(db), nopAuditor{}, nopBroadcaster{}, 1, mapping)
+
+	memberships := func(userID uint) map[uint]models.ProjectMember {
+		var members []models.ProjectMember
+		db.Where("user_id = ?", userID).Find(&members)
+		byProject := map[uint]models.ProjectMember{}
+		for _, member := range members {
+			byProject[member.ProjectID] = member
+		}
+		return byProject
+	}
+
+	alice, err := authenticator.Authenticate(1, "alice", "alice-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.Role != models.RoleShiftLead || alice.ExternalIssuer != auth.LDAPIssuer {
+		t.Fatalf("unexpected user %+v", alice)
+	}
+	members := memberships(alice.ID)
+	if owner := members[leads.ID]; owner.Role != models.RoleShiftLead || !owner.IsOwner || owner.Source != models.MemberSourceGroup {
+		t.Fatalf("unexpected membership in the leads' project: %+v", owner)
+	}
+	if drafter := members[drafting.ID]; drafter.Role != models.RoleDrafter || drafter.IsOwner {
+		t.Fatalf("unexpected membership in the drafting project: %+v", drafter)
+	}
+
+	// bob is in no group with a role, so the default role applies
+	bob, err := authenticator.Authenticate(1, "bob", "bob-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bob.Role != models.RoleDrafter {
+		t.Fatalf("unexpected role for bob %q", bob.Role)
+	}
+	if _, ok := memberships(bob.ID)[leads.ID]; ok {
+		t.Fatal("bob got the leads' project")
+	}
+
+	// Leaving qc-leads in the directory takes the role and the mapped membership away at the next login
+	modifyLeads(t, false)
+	t.Cleanup(func() { modifyLeads(t, true) })
+
+	again, err := authenticator.Authenticate(1, "alice", "alice-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != alice.ID || again.Role != models.RoleDrafter {
+		t.Fatalf("unexpected user after leaving qc-leads %+v", again)
+	}
+	members = memberships(alice.ID)
+	if _, ok := members[leads.ID]; ok {
+		t.Fatal("membership of the left group was kept")
+	}
+	if _, ok := members[drafting.ID]; !ok {
+		t.Fatal("membership of a current group was removed")
+	}
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrMFAChallenge = errors.New("invalid or expired MFA challenge")
+
+// MFAChallenges keeps the logins that passed the password check and wait for a second
+// factor, under mfa:challenge:<token>. A challenge is dropped after MaxFailures wrong codes.
+type MFAChallenges struct {
+	client      *redis.Client
+	ttl         time.Duration
+	maxFailures int
+}
+
+func NewMFAChallenges(client *redis.Client, ttl time.Duration, maxFailures int) *MFAChallenges {
+	return &MFAChallenges{client: client, ttl: ttl, maxFailures: maxFailures}
+}
+
+func mfaChallengeKey(token string) string {
+	return "mfa:challenge:" + token
+}
+
+// TTL is how long a challenge stays valid
+func (c *MFAChallenges) TTL() time.Duration {
+	return c.ttl
+}
+
+// Create starts a challenge for the user and returns its token
+func (c *MFAChallenges) Create(userID uint) (string, error) {
+	token, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	ctx := context.Background()
+	pipe := c.client.TxPipeline()
+	pipe.HSet(ctx, mfaChallengeKey(token), "user_id", userID, "failures", 0)
+	pipe.Expire(ctx, mfaChallengeKey(token), c.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Get returns the user a challenge belongs to
+func (c *MFAChallenges) Get(token string) (uint, error) {
+	value, err := c.client.HGet(context.Background(), mfaChallengeKey(token), "user_id").Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, ErrMFAChallenge
+	}
+	if err != nil {
+		return 0, err
+	}
+	userID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(userID), nil
+}
+
+// failScript counts a wrong code, leaving expired challenges alone so the counter
+// cannot recreate them without a TTL
+var failScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then return 0 end
+if redis.call('HINCRBY', KEYS[1], 'failures', 1) >= tonumber(ARGV[1]) then
+	redis.call('DEL', KEYS[1])
+end
+return 1
+`)
+
+// Fail counts a wrong code and drops the challenge once there were too many
+func (c *MFAChallenges) Fail(token string) error {
+	return failScript.Run(context.Background(), c.client, []string{mfaChallengeKey(token)}, c.maxFailures).Err()
+}
+
+// Consume ends a challenge. Only one caller gets it, so a challenge gives one session.
+func (c *MFAChallenges) Consume(token string) error {
+	deleted, err := c.client.Del(context.Background(), mfaChallengeKey(token)).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrMFAChallenge
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+// OIDCLoginTTL is how long a user has to finish logging in at the identity provider
+const OIDCLoginTTL = 10 * time.Minute
+
+var (
+	// ErrOIDCState means the callback's state is unknown, expired or was already used
+	ErrOIDCState = errors.New("unknown or expired login state")
+	// ErrOIDCBinding means the callback came from a browser other than the one that started the login
+	ErrOIDCBinding = errors.New("login state is bound to another browser")
+)
+
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string // Claim holding the user's groups, "groups" by default
+}
+
+// Identity is what we take from a verified ID token
+type Identity struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+// OIDCProvider runs the authorization code flow with PKCE against an OpenID Connect provider.
+// The PKCE verifier and nonce of a login in progress are kept in Redis under its state,
+// so the callback can land on any instance. A random binding value, kept by the browser
+// in a cookie, ties the state to the browser that started the login.
+type OIDCProvider struct {
+	oauth       oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+	groupsClaim string
+	logins      *redis.Client
+}
+
+// pendingLogin is stored under oidc:login:<state>
+type pendingLogin struct {
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	Binding  string `json:"binding"`
+}
+
+// NewOIDCProvider fetches the provider's discovery document, so the issuer must be reachable at startup
+func NewOIDCProvider(ctx context.Context, cfg OIDCConfig, logins *redis.Client) (*OIDCProvider, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s: %w", cfg.Issuer, err)
+	}
+
+	scopes := append([]string{oidc.ScopeOpenID}, cfg.Scopes...)
+	groupsClaim := cfg.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+
+	return &OIDCProvider{
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier:    provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		groupsClaim: groupsClaim,
+		logins:      logins,
+	}, nil
+}
+
+// AuthCodeURL starts a login. It returns the provider URL to send the browser to and the
+// binding value the browser must present again at the callback.
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context) (string, string, error) {
+	var values [3]string
+	for i := range values {
+		value, err := randomString()
+		if err != nil {
+			return "", "", err
+		}
+		values[i] = value
+	}
+	state := values[0]
+	login := pendingLogin{Verifier: oauth2.GenerateVerifier(), Nonce: values[1], Binding: values[2]}
+
+	data, _ := json.Marshal(login)
+	if err := p.logins.Set(ctx, "oidc:login:"+state, data, OIDCLoginTTL).Err(); err != nil {
+		return "", "", err
+	}
+	return p.oauth.AuthCodeURL(state, oauth2.S256ChallengeOption(login.Verifier), oidc.Nonce(login.Nonce)), login.Binding, nil
+}
+
+// Exchange finishes a login started in the browser holding binding: it redeems the code with
+// the login's PKCE verifier and verifies the ID token's signature, audience, expiry and nonce.
+// Each state works once, even when the binding does not match.
+func (p *OIDCProvider) Exchange(ctx context.Context, state string, code string, binding string) (*Identity, error) {
+	data, err := p.logins.GetDel(ctx, "oidc:login:"+state).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+	var login pendingLogin
+	if err := json.Unmarshal(data, &login); err != nil {
+		return nil, err
+	}
+	if login.Binding == "" || subtle.ConstantTimeCompare([]byte(binding), []byte(login.Binding)) != 1 {
+		return nil, ErrOIDCBinding
+	}
+
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no id_token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("id token: %w", err)
+	}
+	if idToken.Nonce != login.Nonce {
+		return nil, errors.New("id token nonce does not match")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	identity := &Identity{
+		Issuer:  idToken.Issuer,
+		Subject: idToken.Subject,
+		Groups:  stringList(claims[p.groupsClaim]),
+	}
+	identity.Username, _ = claims["preferred_username"].(string)
+	identity.Email, _ = claims["email"].(string)
+	return identity, nil
+}
+
+// stringList accepts a claim sent either as a list or as a single string
+func stringList(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
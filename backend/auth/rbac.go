@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"fmt"
 	"log"
 	"path/filepath"
 	"runtime"
@@ -18,6 +19,11 @@ func InitCasbin(db *gorm.DB) {
 		log.Fatalf("Failed to initialize Casbin adapter: %v", err)
 	}
 
+	// Policies written before organizations had no domain column
+	if err := db.Exec("DELETE FROM casbin_rule WHERE ptype = 'p' AND v3 = ''").Error; err != nil {
+		log.Fatalf("Failed to clean up legacy Casbin policies: %v", err)
+	}
+
 	_, filename, _, _ := runtime.Caller(0)
 	modelPath := filepath.Join(filepath.Dir(filename), "rbac_model.conf")
 
@@ -34,34 +40,51 @@ func InitCasbin(db *gorm.DB) {
 	setupDefaultPolicies()
 }
 
+// Domain is the Casbin domain of an organization
+func Domain(organizationID uint) string {
+	return fmt.Sprintf("org:%d", organizationID)
+}
+
+// Enforce checks a role's permission inside an organization's domain
+func Enforce(role string, organizationID uint, obj string, act string) (bool, error) {
+	return Enforcer.Enforce(role, Domain(organizationID), obj, act)
+}
+
 func setupDefaultPolicies() {
+	// Default policies use the "*" domain and apply to every organization;
+	// organization-specific policies go in the "org:<id>" domain.
 	// Roles: admin, drafter, shift_lead, final_qc
 	// Actions: create, view, claim, submit, approve, release
-	// Resources: drawings
+	// Resources: drawings, projects, users
 
 	// Admin can do everything
-	Enforcer.AddNamedPolicy("p", "admin", "drawings", "*")
+	Enforcer.AddNamedPolicy("p", "admin", "*", "drawings", "*")
+	Enforcer.AddNamedPolicy("p", "admin", "*", "projects", "*")
+	Enforcer.AddNamedPolicy("p", "admin", "*", "users", "*")
 
 	// Drafter
-	Enforcer.AddNamedPolicy("p", "drafter", "drawings", "view")
-	Enforcer.AddNamedPolicy("p", "drafter", "drawings", "claim")
-	Enforcer.AddNamedPolicy("p", "drafter", "drawings", "submit")
-	Enforcer.AddNamedPolicy("p", "drafter", "drawings", "release")
+	Enforcer.AddNamedPolicy("p", "drafter", "*", "drawings", "view")
+	Enforcer.AddNamedPolicy("p", "drafter", "*", "projects", "view")
+	Enforcer.AddNamedPolicy("p", "drafter", "*", "drawings", "claim")
+	Enforcer.AddNamedPolicy("p", "drafter", "*", "drawings", "submit")
+	Enforcer.AddNamedPolicy("p", "drafter", "*", "drawings", "release")
 
 	// Shift Lead
-	Enforcer.AddNamedPolicy("p", "shift_lead", "drawings", "view")
-	Enforcer.AddNamedPolicy("p", "shift_lead", "drawings", "claim")
-	Enforcer.AddNamedPolicy("p", "shift_lead", "drawings", "submit")
-	Enforcer.AddNamedPolicy("p", "shift_lead", "drawings", "release")
-	Enforcer.AddNamedPolicy("p", "shift_lead", "drawings", "reject")
+	Enforcer.AddNamedPolicy("p", "shift_lead", "*", "drawings", "view")
+	Enforcer.AddNamedPolicy("p", "shift_lead", "*", "projects", "view")
+	Enforcer.AddNamedPolicy("p", "shift_lead", "*", "drawings", "claim")
+	Enforcer.AddNamedPolicy("p", "shift_lead", "*", "drawings", "submit")
+	Enforcer.AddNamedPolicy("p", "shift_lead", "*", "drawings", "release")
+	Enforcer.AddNamedPolicy("p", "shift_lead", "*", "drawings", "reject")
 
 	// Final QC
-	Enforcer.AddNamedPolicy("p", "final_qc", "drawings", "view")
-	Enforcer.AddNamedPolicy("p", "final_qc", "drawings", "claim")
-	Enforcer.AddNamedPolicy("p", "final_qc", "drawings", "submit")
-	Enforcer.AddNamedPolicy("p", "final_qc", "drawings", "approve")
-	Enforcer.AddNamedPolicy("p", "final_qc", "drawings", "release")
-	Enforcer.AddNamedPolicy("p", "final_qc", "drawings", "reject")
+	Enforcer.AddNamedPolicy("p", "final_qc", "*", "drawings", "view")
+	Enforcer.AddNamedPolicy("p", "final_qc", "*", "projects", "view")
+	Enforcer.AddNamedPolicy("p", "final_qc", "*", "drawings", "claim")
+	Enforcer.AddNamedPolicy("p", "final_qc", "*", "drawings", "submit")
+	Enforcer.AddNamedPolicy("p", "final_qc", "*", "drawings", "approve")
+	Enforcer.AddNamedPolicy("p", "final_qc", "*", "drawings", "release")
+	Enforcer.AddNamedPolicy("p", "final_qc", "*", "drawings", "reject")
 
 	err := Enforcer.SavePolicy()
 	if err != nil {
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Revocations is the server-side revocation list. It is nil until InitRevocations
+// runs, in which case no token is considered revoked.
+var Revocations *RevocationList
+
+// RevocationList stores revoked token IDs and per-user "revoked before" timestamps
+// (Unix milliseconds) in Redis. Lookups are cached in a small LRU, so another instance's revocation
+// can take up to the cache TTL to be seen here.
+type RevocationList struct {
+	client *redis.Client
+	cache  *revocationCache
+}
+
+func InitRevocations(client *redis.Client, cacheSize int, cacheTTL time.Duration) {
+	Revocations = &RevocationList{
+		client: client,
+		cache:  newRevocationCache(cacheSize, cacheTTL),
+	}
+}
+
+// legacyRevocationCutoff separates revocation times stored in seconds from those in
+// milliseconds; as milliseconds it is early 2001
+const legacyRevocationCutoff = 1_000_000_000_000
+
+func tokenKey(jti string) string {
+	return "revoked:jti:" + jti
+}
+
+func userKey(userID uint) string {
+	return fmt.Sprintf("revoked:user:%d", userID)
+}
+
+// RevokeToken revokes a single access token until it would have expired anyway
+func (r *RevocationList) RevokeToken(userID uint, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	if err := r.client.Set(context.Background(), tokenKey(jti), 1, ttl).Err(); err != nil {
+		return err
+	}
+	r.cache.put(jti, userID, true)
+	return nil
+}
+
+// RevokeUser revokes every access token issued to the user before at
+func (r *RevocationList) RevokeUser(userID uint, at time.Time) error {
+	if err := r.client.Set(context.Background(), userKey(userID), at.UnixMilli(), AccessTokenTTL).Err(); err != nil {
+		return err
+	}
+	r.cache.forgetUser(userID)
+	return nil
+}
+
+// IsRevoked reports whether the token was revoked on its own or with all of its user's sessions
+func (r *RevocationList) IsRevoked(claims *Claims) (bool, error) {
+	if revoked, ok := r.cache.get(claims.ID); ok {
+		return revoked, nil
+	}
+
+	values, err := r.client.MGet(context.Background(), tokenKey(claims.ID), userKey(claims.UserID)).Result()
+	if err != nil {
+		return false, err
+	}
+	revoked := values[0] != nil
+	if !revoked && values[1] != nil && claims.IssuedAt != nil {
+		revokedBefore, err := strconv.ParseInt(values[1].(string), 10, 64)
+		if err != nil {
+			return false, err
+		}
+		if revokedBefore < legacyRevocationCutoff {
+			// Written in whole seconds by an older version: cover that entire second
+			revokedBefore = (revokedBefore + 1) * 1000
+		}
+		revoked = claims.IssuedAt.UnixMilli() < revokedBefore
+	}
+
+	r.cache.put(claims.ID, claims.UserID, revoked)
+	return revoked, nil
+}
+
+// Authenticate validates a token and checks it against the revocation list
+func Authenticate(tokenString string) (*Claims, error) {
+	claims, err := ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if Revocations == nil {
+		return claims, nil
+	}
+	revoked, err := Revocations.IsRevoked(claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("token revoked")
+	}
+	return claims, nil
+}
+
+// revocationCache is a bounded LRU of revocation lookups keyed by token ID
+type revocationCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type revocationEntry struct {
+	jti       string
+	userID    uint
+	revoked   bool
+	expiresAt time.Time
+}
+
+func newRevocationCache(size int, ttl time.Duration) *revocationCache {
+	return &revocationCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *revocationCache) get(jti string) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[jti]
+	if !ok {
+		return false, false
+	}
+	entry := el.Value.(*revocationEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.entries, jti)
+		return false, false
+	}
+	c.order.MoveToFront(el)
+	return entry.revoked, true
+}
+
+func (c *revocationCache) put(jti string, userID uint, revoked bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &revocationEntry{jti: jti, userID: userID, revoked: revoked, expiresAt: time.Now().Add(c.ttl)}
+	if el, ok := c.entries[jti]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[jti] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*revocationEntry).jti)
+	}
+}
+
+func (c *revocationCache) forgetUser(userID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for jti, el := range c.entries {
+		if el.Value.(*revocationEntry).userID == userID {
+			c.order.Remove(el)
+			delete(c.entries, jti)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ThrottleConfig sets how quickly one account or IP is slowed down. After FreeAttempts
+// failures every attempt waits BaseDelay, doubled per further failure, and at
+// LockoutAttempts the key is locked for LockoutDuration. Failures are forgotten after Window.
+type ThrottleConfig struct {
+	FreeAttempts    int
+	LockoutAttempts int
+	BaseDelay       time.Duration
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// ThrottledError is returned while an account or IP is backing off or locked
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+	ByIP       bool
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("login throttled for %s", e.RetryAfter)
+}
+
+// LoginAttempt is a reserved attempt. It already counts as a failure, so parallel
+// guesses cannot get past the backoff; Succeeded takes it back.
+type LoginAttempt struct {
+	Account         string
+	IP              string
+	AccountFailures int
+	AccountLocked   bool // this attempt locks the account unless it succeeds
+	IPFailures      int
+	IPLocked        bool
+	ipBlocked       bool
+}
+
+// LoginThrottle keeps failed login counters per account and per IP in Redis
+type LoginThrottle struct {
+	client  *redis.Client
+	account ThrottleConfig
+	ip      ThrottleConfig
+}
+
+func NewLoginThrottle(client *redis.Client, account ThrottleConfig, ip ThrottleConfig) *LoginThrottle {
+	return &LoginThrottle{client: client, account: account, ip: ip}
+}
+
+func accountCountKey(account string) string { return "login:fail:account:" + account }
+func accountBlockKey(account string) string { return "login:block:account:" + account }
+func ipCountKey(ip string) string           { return "login:fail:ip:" + ip }
+func ipBlockKey(ip string) string           { return "login:block:ip:" + ip }
+
+// beginScript checks both blocks, then counts the attempt against both keys and sets
+// the next block. It returns {0, ttl, locked, by_ip} when blocked and
+// {1, account_failures, account_locked, ip_failures, ip_locked, ip_blocked} otherwise.
+var beginScript = redis.NewScript(`
+local function blocked(key)
+	local ttl = redis.call('PTTL', key)
+	if ttl > 0 then
+		local locked = 0
+		if redis.call('GET', key) == 'locked' then locked = 1 end
+		return ttl, locked
+	end
+	return 0, 0
+end
+
+local function count(countKey, blockKey, free, lockout, base, lockoutMs, window)
+	local n = redis.call('INCR', countKey)
+	redis.call('PEXPIRE', countKey, window)
+	if n >= lockout then
+		redis.call('SET', blockKey, 'locked', 'PX', lockoutMs)
+		return n, 1, 1
+	end
+	if n > free then
+		local delay = math.min(base * 2 ^ (n - free - 1), lockoutMs)
+		redis.call('SET', blockKey, 'backoff', 'PX', math.floor(delay))
+		return n, 0, 1
+	end
+	return n, 0, 0
+end
+
+local ttl, locked = blocked(KEYS[4])
+if ttl > 0 then return {0, ttl, locked, 1} end
+ttl, locked = blocked(KEYS[2])
+if ttl > 0 then return {0, ttl, locked, 0} end
+
+local a = {}
+for i = 1, 10 do a[i] = tonumber(ARGV[i]) end
+local accountFailures, accountLocked = count(KEYS[1], KEYS[2], a[1], a[2], a[3], a[4], a[5])
+local ipFailures, ipLocked, ipBlocked = count(KEYS[3], KEYS[4], a[6], a[7], a[8], a[9], a[10])
+return {1, accountFailures, accountLocked, ipFailures, ipLocked, ipBlocked}
+`)
+
+// Begin reserves an attempt, or returns a *ThrottledError while the account or IP is blocked
+func (t *LoginThrottle) Begin(account string, ip string) (*LoginAttempt, error) {
+	keys := []string{accountCountKey(account), accountBlockKey(account), ipCountKey(ip), ipBlockKey(ip)}
+	args := append(t.account.args(), t.ip.args()...)
+	result, err := beginScript.Run(context.Background(), t.client, keys, args...).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	if result[0] == 0 {
+		return nil, &ThrottledError{
+			RetryAfter: time.Duration(result[1]) * time.Millisecond,
+			Locked:     result[2] == 1,
+			ByIP:       result[3] == 1,
+		}
+	}
+	return &LoginAttempt{
+		Account:         account,
+		IP:              ip,
+		AccountFailures: int(result[1]),
+		AccountLocked:   result[2] == 1,
+		IPFailures:      int(result[3]),
+		IPLocked:        result[4] == 1,
+		ipBlocked:       result[5] == 1,
+	}, nil
+}
+
+// succeededScript leaves the IP counter alone once it has expired, so DECR cannot
+// recreate it without a TTL
+var succeededScript = redis.NewScript(`
+redis.call('DEL', KEYS[1], KEYS[2])
+if redis.call('EXISTS', KEYS[3]) == 1 then redis.call('DECR', KEYS[3]) end
+if ARGV[1] == '1' then redis.call('DEL', KEYS[4]) end
+return 1
+`)
+
+// Succeeded clears the account's failures and takes the attempt back from the IP's count
+func (t *LoginThrottle) Succeeded(attempt *LoginAttempt) error {
+	keys := []string{accountCountKey(attempt.Account), accountBlockKey(attempt.Account), ipCountKey(attempt.IP), ipBlockKey(attempt.IP)}
+	ipBlocked := "0"
+	if attempt.ipBlocked {
+		ipBlocked = "1"
+	}
+	return succeededScript.Run(context.Background(), t.client, keys, ipBlocked).Err()
+}
+
+// Unlock clears an account's failures and lockout
+func (t *LoginThrottle) Unlock(account string) error {
+	return t.client.Del(context.Background(), accountCountKey(account), accountBlockKey(account)).Err()
+}
+
+func (c ThrottleConfig) args() []interface{} {
+	return []interface{}{
+		c.FreeAttempts,
+		c.LockoutAttempts,
+		c.BaseDelay.Milliseconds(),
+		c.LockoutDuration.Milliseconds(),
+		c.Window.Milliseconds(),
+	}
+}
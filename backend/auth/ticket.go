@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrInvalidTicket = errors.New("invalid, expired or used stream ticket")
+
+// StreamTicket lets an EventSource, which cannot send headers, open one stream.
+// It carries the session it was minted from, so revoking that session still ends the stream.
+type StreamTicket struct {
+	UserID         uint   `json:"user_id"`
+	OrganizationID uint   `json:"org_id"`
+	Role           string `json:"role"`
+	ProjectID      uint   `json:"project_id"`
+	SessionID      string `json:"session_id"` // jti of the access token
+	IssuedAtMs     int64  `json:"iat_ms"`     // of the access token, in Unix milliseconds
+}
+
+// StreamTickets stores tickets in Redis under sse:ticket:<ticket> until they are redeemed or expire
+type StreamTickets struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewStreamTickets(client *redis.Client, ttl time.Duration) *StreamTickets {
+	return &StreamTickets{client: client, ttl: ttl}
+}
+
+// TTL is how long a ticket can wait to be redeemed
+func (t *StreamTickets) TTL() time.Duration {
+	return t.ttl
+}
+
+// Issue mints a ticket for the session described by the access token's claims
+func (t *StreamTickets) Issue(claims *Claims, projectID uint) (string, error) {
+	ticket := StreamTicket{
+		UserID:         claims.UserID,
+		OrganizationID: claims.OrganizationID,
+		Role:           claims.Role,
+		ProjectID:      projectID,
+		SessionID:      claims.ID,
+	}
+	if claims.IssuedAt != nil {
+		ticket.IssuedAtMs = claims.IssuedAt.UnixMilli()
+	}
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	if err := t.client.Set(context.Background(), "sse:ticket:"+raw, data, t.ttl).Err(); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// Redeem uses up a ticket. It fails if the session was revoked after the ticket was minted.
+func (t *StreamTickets) Redeem(raw string) (*StreamTicket, error) {
+	data, err := t.client.GetDel(context.Background(), "sse:ticket:"+raw).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidTicket
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ticket StreamTicket
+	if err := json.Unmarshal(data, &ticket); err != nil {
+		return nil, err
+	}
+
+	if Revocations != nil {
+		revoked, err := Revocations.IsRevoked(&Claims{
+			UserID:           ticket.UserID,
+			RegisteredClaims: jwt.RegisteredClaims{ID: ticket.SessionID, IssuedAt: jwt.NewNumericDate(time.UnixMilli(ticket.IssuedAtMs))},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrInvalidTicket
+		}
+	}
+	return &ticket, nil
+}
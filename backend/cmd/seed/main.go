@@ -13,20 +13,26 @@ func main() {
 	cfg := config.LoadConfig()
 	database.InitDB(cfg.DBURL)
 
+	// Everything is seeded into the default organization, created by InitDB
+	var org models.Organization
+	if err := database.DB.Where("slug = ?", models.DefaultOrganizationSlug).First(&org).Error; err != nil {
+		log.Fatalf("Default organization missing: %v", err)
+	}
+
 	// Seed Projects
-	p1 := models.Project{Name: "Aerodynamics Suite", Description: "Advanced CFD and wind tunnel models."}
-	database.DB.Where("name = ?", p1.Name).FirstOrCreate(&p1)
+	p1 := models.Project{OrganizationID: org.ID, Name: "Aerodynamics Suite", Description: "Advanced CFD and wind tunnel models."}
+	database.DB.Where("organization_id = ? AND name = ?", org.ID, p1.Name).FirstOrCreate(&p1)
 
-	p2 := models.Project{Name: "Power Unit", Description: "MGU-H and Internal Combustion engine design."}
-	database.DB.Where("name = ?", p2.Name).FirstOrCreate(&p2)
+	p2 := models.Project{OrganizationID: org.ID, Name: "Power Unit", Description: "MGU-H and Internal Combustion engine design."}
+	database.DB.Where("organization_id = ? AND name = ?", org.ID, p2.Name).FirstOrCreate(&p2)
 
 	users := []models.User{
-		{Username: "admin", Role: models.RoleAdmin},
-		{Username: "drafter1", Role: models.RoleDrafter},
-		{Username: "drafter2", Role: models.RoleDrafter},
-		{Username: "lead1", Role: models.RoleShiftLead},
-		{Username: "lead2", Role: models.RoleShiftLead},
-		{Username: "qc1", Role: models.RoleFinalQC},
+		{OrganizationID: org.ID, Username: "admin", Role: models.RoleAdmin},
+		{OrganizationID: org.ID, Username: "drafter1", Role: models.RoleDrafter},
+		{OrganizationID: org.ID, Username: "drafter2", Role: models.RoleDrafter},
+		{OrganizationID: org.ID, Username: "lead1", Role: models.RoleShiftLead},
+		{OrganizationID: org.ID, Username: "lead2", Role: models.RoleShiftLead},
+		{OrganizationID: org.ID, Username: "qc1", Role: models.RoleFinalQC},
 	}
 
 	password := "password123"
@@ -34,7 +40,7 @@ func main() {
 
 	for i := range users {
 		users[i].PasswordHash = string(hashedPassword)
-		if err := database.DB.Where("username = ?", users[i].Username).FirstOrCreate(&users[i]).Error; err != nil {
+		if err := database.DB.Where("organization_id = ? AND username = ?", org.ID, users[i].Username).FirstOrCreate(&users[i]).Error; err != nil {
 			log.Printf("Error seeding user %s: %v", users[i].Username, err)
 		}
 
@@ -43,6 +49,7 @@ func main() {
 			ProjectID: p1.ID,
 			UserID:    users[i].ID,
 			Role:      users[i].Role,
+			IsOwner:   users[i].Role == models.RoleAdmin,
 		})
 	}
 
@@ -4,99 +4,193 @@ import (
 	"backend/auth"
 	"backend/models"
 	"backend/repositories"
-	"errors"
+	"backend/services"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/microcosm-cc/bluemonday"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Auth struct {
-	repo         repositories.UserRepository
-	strictPolicy *bluemonday.Policy
+	logins        *services.Logins
+	mfa           *services.MFA
+	organizations repositories.OrganizationRepository
+	tokens        *services.Tokens
+	accounts      *services.Accounts
+	strictPolicy  *bluemonday.Policy
 }
 
-func NewAuth(repo repositories.UserRepository) *Auth {
+func NewAuth(logins *services.Logins, mfa *services.MFA, organizations repositories.OrganizationRepository, tokens *services.Tokens, accounts *services.Accounts) *Auth {
 	return &Auth{
-		repo:         repo,
-		strictPolicy: bluemonday.StrictPolicy(),
+		logins:        logins,
+		mfa:           mfa,
+		organizations: organizations,
+		tokens:        tokens,
+		accounts:      accounts,
+		strictPolicy:  bluemonday.StrictPolicy(),
 	}
 }
 
+// Organization is the organization slug; it defaults to the default organization.
+// With an invitation token the organization and role come from the invitation.
 type RegisterRequest struct {
-	Username string          `json:"username" binding:"required,alphanum,min=3,max=30"`
-	Password string          `json:"password" binding:"required,min=8"`
-	Role     models.UserRole `json:"role" binding:"required,oneof=admin drafter shift_lead final_qc"`
+	Organization    string `json:"organization" binding:"omitempty,max=50"`
+	Username        string `json:"username" binding:"required,alphanum,min=3,max=30"`
+	Email           string `json:"email" binding:"omitempty,email,max=255"`
+	Password        string `json:"password" binding:"required"`
+	InvitationToken string `json:"invitation_token" binding:"omitempty,max=100"`
 }
 
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Organization string `json:"organization" binding:"omitempty,max=50"`
+	Username     string `json:"username" binding:"required"`
+	Password     string `json:"password" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// organization looks up the organization named in a register or login request
+func (ctrl *Auth) organization(slug string) (*models.Organization, error) {
+	if slug == "" {
+		slug = models.DefaultOrganizationSlug
+	}
+	return ctrl.organizations.GetBySlug(slug)
 }
 
 func (ctrl *Auth) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": getErrorMessage(err)})
+		respondBindError(c, err)
 		return
 	}
 
 	req.Username = ctrl.strictPolicy.Sanitize(req.Username)
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
-		return
+	var organizationID uint
+	if req.InvitationToken == "" {
+		organization, err := ctrl.organization(req.Organization)
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, "UNKNOWN_ORGANIZATION", "Unknown organization")
+			return
+		}
+		organizationID = organization.ID
 	}
 
-	user := models.User{
-		Username:     req.Username,
-		PasswordHash: string(hashedPassword),
-		Role:         req.Role,
+	user, err := ctrl.accounts.Register(organizationID, req.Username, req.Email, req.Password, req.InvitationToken)
+	if err != nil {
+		respondError(c, err)
+		return
 	}
 
-	if err := ctrl.repo.Create(&user); err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	if user.Status == models.UserStatusPending {
+		c.JSON(http.StatusAccepted, gin.H{"message": "Registration received; an administrator has to approve the account", "status": user.Status})
 		return
 	}
-
-	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
+	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully", "status": user.Status})
 }
 
 func (ctrl *Auth) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": getErrorMessage(err)})
+		respondBindError(c, err)
 		return
 	}
 
-	user, err := ctrl.repo.GetByUsername(req.Username)
+	organization, err := ctrl.organization(req.Organization)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+		abortWithError(c, http.StatusUnauthorized, "INVALID_CREDENTIALS", "Invalid username or password")
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	user, retryAfter, err := ctrl.logins.Login(organization.ID, req.Username, req.Password, c.ClientIP())
+	if err != nil {
+		if retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		respondError(c, err)
 		return
 	}
 
-	token, err := auth.GenerateToken(user.ID, string(user.Role))
+	// Users with MFA, or whose role requires it, continue at /login/mfa
+	challenge, err := ctrl.mfa.Begin(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		respondError(c, err)
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":        true,
+			"mfa_token":           challenge.Token,
+			"enrollment_required": challenge.EnrollmentRequired,
+			"expires_in":          int(challenge.ExpiresIn.Seconds()),
+		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token":   token,
-		"role":    user.Role,
-		"user_id": user.ID,
-	})
+	pair, err := ctrl.tokens.Issue(user)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	respondTokens(c, pair)
+}
+
+// Refresh rotates a refresh token and returns a new access token
+func (ctrl *Auth) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	pair, err := ctrl.tokens.Refresh(req.RefreshToken)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	respondTokens(c, pair)
+}
+
+// Logout revokes the access token used for the request, and the refresh token if one is sent
+func (ctrl *Auth) Logout(c *gin.Context) {
+	var req LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindError(c, err)
+			return
+		}
+	}
+
+	claims, ok := c.Get("claims")
+	if !ok {
+		abortWithError(c, http.StatusBadRequest, "NOT_A_SESSION", "API keys cannot log out; revoke the key instead")
+		return
+	}
+	if err := ctrl.tokens.Logout(claims.(*auth.Claims), req.RefreshToken); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondTokens(c *gin.Context, pair *services.TokenPair) {
+	c.JSON(http.StatusOK, tokenResponse(pair))
+}
+
+func tokenResponse(pair *services.TokenPair) gin.H {
+	return gin.H{
+		"token":         pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    int(pair.ExpiresIn.Seconds()),
+		"role":          pair.User.Role,
+		"user_id":       pair.User.ID,
+		"org_id":        pair.User.OrganizationID,
+	}
 }
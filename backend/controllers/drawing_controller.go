@@ -2,26 +2,22 @@ package controllers
 
 import (
 	"backend/models"
-	"backend/repositories"
 	"backend/services"
-	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/microcosm-cc/bluemonday"
 )
 
 type Drawing struct {
-	repo      repositories.DrawingRepository
 	service   *services.Drawing
 	ugcPolicy *bluemonday.Policy
 }
 
-func NewDrawing(repo repositories.DrawingRepository, service *services.Drawing) *Drawing {
+func NewDrawing(service *services.Drawing) *Drawing {
 	return &Drawing{
-		repo:      repo,
 		service:   service,
 		ugcPolicy: bluemonday.UGCPolicy(),
 	}
@@ -29,14 +25,19 @@ func NewDrawing(repo repositories.DrawingRepository, service *services.Drawing)
 
 func (ctrl *Drawing) GetDrawings(c *gin.Context) {
 	projectIDStr := c.Query("project_id")
-	projectID := 0
+	var projectID uint64
 	if projectIDStr != "" {
-		projectID, _ = strconv.Atoi(projectIDStr)
+		var err error
+		projectID, err = strconv.ParseUint(projectIDStr, 10, 32)
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, CodeInvalidID, "Invalid project_id")
+			return
+		}
 	}
 
-	drawings, err := ctrl.repo.GetByProject(uint(projectID))
+	drawings, err := ctrl.service.List(c.GetUint("org_id"), uint(projectID), c.MustGet("user_id").(uint), c.MustGet("role").(string))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch drawings"})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, drawings)
@@ -50,8 +51,9 @@ type CreateDrawingRequest struct {
 
 func (ctrl *Drawing) CreateDrawing(c *gin.Context) {
 	var req CreateDrawingRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": getErrorMessage(err)})
+	// The body was already read by ProjectFromBody
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		respondBindError(c, err)
 		return
 	}
 
@@ -67,13 +69,8 @@ func (ctrl *Drawing) CreateDrawing(c *gin.Context) {
 		AuthorID:     c.MustGet("user_id").(uint),
 	}
 
-	if err := ctrl.repo.Create(&drawing); err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			c.JSON(http.StatusConflict, gin.H{"error": "A drawing with this title already exists in this project"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create drawing"})
+	if err := ctrl.service.Create(&drawing); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -98,13 +95,17 @@ func (ctrl *Drawing) RejectDrawing(c *gin.Context) {
 
 func (ctrl *Drawing) handleWorkflowAction(c *gin.Context, action models.Action) {
 	idStr := c.Param("id")
-	id, _ := strconv.ParseUint(idStr, 10, 32)
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "Invalid drawing id")
+		return
+	}
 	userID := c.MustGet("user_id").(uint)
 	userRole := c.MustGet("role").(string)
 
 	drawing, err := ctrl.service.ProcessWorkflowAction(uint(id), userID, userRole, action)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -113,3 +114,76 @@ func (ctrl *Drawing) handleWorkflowAction(c *gin.Context, action models.Action)
 		"drawing": drawing,
 	})
 }
+
+type BatchActionItem struct {
+	ID              uint          `json:"id" binding:"required"`
+	Action          models.Action `json:"action" binding:"required,oneof=claim submit release reject"`
+	ExpectedVersion *int64        `json:"expected_version" binding:"required"`
+}
+
+type BatchActionRequest struct {
+	Mode  string            `json:"mode" binding:"required,oneof=atomic best_effort"`
+	Items []BatchActionItem `json:"items" binding:"required,min=1,max=100,dive"`
+}
+
+type BatchActionResult struct {
+	ID      uint            `json:"id"`
+	Action  models.Action   `json:"action"`
+	Success bool            `json:"success"`
+	Error   string          `json:"error,omitempty"`
+	Code    string          `json:"code,omitempty"`
+	Drawing *models.Drawing `json:"drawing,omitempty"`
+}
+
+func (ctrl *Drawing) BatchWorkflowActions(c *gin.Context) {
+	var req BatchActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	// API keys need a scope for every action in the batch
+	if key, ok := c.Get("api_key"); ok {
+		for _, item := range req.Items {
+			if !services.ScopesAllow(key.(*models.APIKey).Scopes, "drawings", string(item.Action)) {
+				abortWithError(c, http.StatusForbidden, "SCOPE_NOT_GRANTED", "API key scope does not allow this action")
+				return
+			}
+		}
+	}
+
+	userID := c.MustGet("user_id").(uint)
+	userRole := c.MustGet("role").(string)
+
+	items := make([]services.BatchItem, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = services.BatchItem{
+			DrawingID:       item.ID,
+			Action:          item.Action,
+			ExpectedVersion: *item.ExpectedVersion,
+		}
+	}
+
+	processed, err := ctrl.service.ProcessBatch(items, userID, userRole, req.Mode == "atomic")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	results := make([]BatchActionResult, len(processed))
+	for i, r := range processed {
+		results[i] = BatchActionResult{ID: r.DrawingID, Action: r.Action}
+		if r.Err != nil {
+			results[i].Error = r.Err.Error()
+			results[i].Code = errorCode(r.Err)
+			continue
+		}
+		results[i].Success = true
+		results[i].Drawing = r.Drawing
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mode":    req.Mode,
+		"results": results,
+	})
+}
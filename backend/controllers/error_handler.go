@@ -1,42 +1,68 @@
 package controllers
 
 import (
-	"github.com/go-playground/validator/v10"
+	"backend/services"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes for failures detected in the HTTP layer. Domain failures carry
+// their own code from services.Error.
+const (
+	CodeInvalidInput  = "INVALID_INPUT"
+	CodeInvalidID     = "INVALID_ID"
+	CodeInternalError = "INTERNAL_ERROR"
 )
 
-func getErrorMessage(err error) string {
-	if ve, ok := err.(validator.ValidationErrors); ok {
-		fe := ve[0]
-		switch fe.Field() {
-		case "Username":
-			if fe.Tag() == "required" {
-				return "Username is required"
-			}
-			if fe.Tag() == "alphanum" {
-				return "Username must be alphanumeric"
-			}
-			if fe.Tag() == "min" || fe.Tag() == "max" {
-				return "Username must be between 3 and 30 characters"
-			}
-		case "Password":
-			if fe.Tag() == "required" {
-				return "Password is required"
-			}
-			if fe.Tag() == "min" {
-				return "Password must be at least 8 characters long"
-			}
-		case "Role":
-			return "Please select a valid role"
-		case "Title":
-			if fe.Tag() == "required" {
-				return "Title is required"
-			}
-			if fe.Tag() == "min" || fe.Tag() == "max" {
-				return "Title must be between 3 and 100 characters"
-			}
-		case "ProjectID":
-			return "Valid Project ID is required"
-		}
+// abortWithError writes the standard error envelope: {"error": message, "code": code}
+func abortWithError(c *gin.Context, status int, code string, message string) {
+	c.AbortWithStatusJSON(status, gin.H{"error": message, "code": code})
+}
+
+// respondError maps a service error to its HTTP status. Anything that is not a
+// services.Error is logged and reported as a generic internal error.
+func respondError(c *gin.Context, err error) {
+	var domainErr *services.Error
+	if !errors.As(err, &domainErr) {
+		domainErr = services.Internal(err)
+	}
+	if domainErr.Kind == services.KindInternal {
+		log.Printf("internal error on %s %s: %v", c.Request.Method, c.FullPath(), domainErr.Err)
+	}
+	abortWithError(c, StatusFor(domainErr.Kind), domainErr.Code, domainErr.Message)
+}
+
+// StatusFor maps an error kind to its HTTP status. The middlewares use it too, so every
+// layer answers a domain error the same way.
+func StatusFor(kind services.ErrorKind) int {
+	switch kind {
+	case services.KindInvalid:
+		return http.StatusBadRequest
+	case services.KindNotFound:
+		return http.StatusNotFound
+	case services.KindForbidden:
+		return http.StatusForbidden
+	case services.KindConflict:
+		return http.StatusConflict
+	case services.KindUnprocessable:
+		return http.StatusUnprocessableEntity
+	case services.KindUnauthenticated:
+		return http.StatusUnauthorized
+	case services.KindTooManyRequests:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorCode returns the stable code for an error, for per-item results
+func errorCode(err error) string {
+	var domainErr *services.Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
 	}
-	return "Invalid input data"
+	return CodeInternalError
 }
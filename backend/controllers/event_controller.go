@@ -6,31 +6,61 @@ import (
 	"net/http"
 	"strconv"
 
+	"backend/auth"
 	"backend/realtime"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 type Event struct {
 	broadcaster realtime.Broadcaster
+	tickets     *auth.StreamTickets
 }
 
-func NewEvent(broadcaster realtime.Broadcaster) *Event {
+func NewEvent(broadcaster realtime.Broadcaster, tickets *auth.StreamTickets) *Event {
 	return &Event{
 		broadcaster: broadcaster,
+		tickets:     tickets,
 	}
 }
 
+type StreamTicketRequest struct {
+	ProjectID uint `json:"project_id" binding:"required"`
+}
+
+// CreateTicket mints a single-use ticket for opening the project's stream with EventSource.
+// API keys send their header to /events directly.
+func (ctrl *Event) CreateTicket(c *gin.Context) {
+	var req StreamTicketRequest
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	claims, ok := c.Get("claims")
+	if !ok {
+		abortWithError(c, http.StatusBadRequest, "NOT_A_SESSION", "API keys open streams with the X-API-Key header")
+		return
+	}
+	ticket, err := ctrl.tickets.Issue(claims.(*auth.Claims), req.ProjectID)
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, CodeInternalError, "Failed to create stream ticket")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"ticket": ticket, "expires_in": int(ctrl.tickets.TTL().Seconds())})
+}
+
 func (ctrl *Event) StreamEvents(c *gin.Context) {
 	projectIDStr := c.Query("project_id")
 	if projectIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id is required"})
+		abortWithError(c, http.StatusBadRequest, CodeInvalidInput, "project_id is required")
 		return
 	}
 
 	projectID, err := strconv.ParseUint(projectIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project_id"})
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "invalid project_id")
 		return
 	}
 
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Invitation struct {
+	accounts *services.Accounts
+}
+
+func NewInvitation(accounts *services.Accounts) *Invitation {
+	return &Invitation{accounts: accounts}
+}
+
+type InvitationMembershipRequest struct {
+	ProjectID uint            `json:"project_id" binding:"required"`
+	Role      models.UserRole `json:"role" binding:"required,oneof=admin drafter shift_lead final_qc"`
+	IsOwner   bool            `json:"is_owner"`
+}
+
+// ExpiresInHours defaults to services.DefaultInvitationTTL
+type CreateInvitationRequest struct {
+	Role           models.UserRole               `json:"role" binding:"required,oneof=admin drafter shift_lead final_qc"`
+	Note           string                        `json:"note" binding:"max=255"`
+	ExpiresInHours int                           `json:"expires_in_hours" binding:"omitempty,min=1,max=720"`
+	Memberships    []InvitationMembershipRequest `json:"memberships" binding:"omitempty,max=50,dive"`
+}
+
+// CreateInvitation returns the invitation together with its token, which is not retrievable later
+func (ctrl *Invitation) CreateInvitation(c *gin.Context) {
+	var req CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	spec := services.InvitationSpec{
+		Role: req.Role,
+		Note: req.Note,
+		TTL:  time.Duration(req.ExpiresInHours) * time.Hour,
+	}
+	for _, m := range req.Memberships {
+		spec.Memberships = append(spec.Memberships, models.InvitationMembership{
+			ProjectID: m.ProjectID,
+			Role:      m.Role,
+			IsOwner:   m.IsOwner,
+		})
+	}
+
+	invitation, token, err := ctrl.accounts.Invite(c.GetUint("org_id"), c.MustGet("user_id").(uint), spec)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"invitation": invitation, "token": token})
+}
+
+func (ctrl *Invitation) GetInvitations(c *gin.Context) {
+	invitations, err := ctrl.accounts.ListInvitations(c.GetUint("org_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, invitations)
+}
+
+func (ctrl *Invitation) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "invalid invitation id")
+		return
+	}
+
+	if err := ctrl.accounts.RevokeInvitation(uint(id), c.GetUint("org_id"), c.MustGet("user_id").(uint)); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Member struct {
+	service *services.Membership
+}
+
+func NewMember(service *services.Membership) *Member {
+	return &Member{service: service}
+}
+
+type AddMemberRequest struct {
+	UserID  uint            `json:"user_id" binding:"required"`
+	Role    models.UserRole `json:"role" binding:"required,oneof=admin drafter shift_lead final_qc"`
+	IsOwner bool            `json:"is_owner"`
+}
+
+type UpdateMemberRequest struct {
+	Role    *models.UserRole `json:"role" binding:"omitempty,oneof=admin drafter shift_lead final_qc"`
+	IsOwner *bool            `json:"is_owner"`
+}
+
+func (ctrl *Member) GetMembers(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	members, err := ctrl.service.List(projectID, c.MustGet("user_id").(uint), c.MustGet("role").(string))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+func (ctrl *Member) AddMember(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	member := models.ProjectMember{
+		ProjectID: projectID,
+		UserID:    req.UserID,
+		Role:      req.Role,
+		IsOwner:   req.IsOwner,
+	}
+	if err := ctrl.service.Add(&member, c.MustGet("user_id").(uint), c.MustGet("role").(string)); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, member)
+}
+
+func (ctrl *Member) UpdateMember(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	changes := services.MemberChanges{Role: req.Role, IsOwner: req.IsOwner}
+	member, err := ctrl.service.Update(projectID, userID, changes, c.MustGet("user_id").(uint), c.MustGet("role").(string))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, member)
+}
+
+func (ctrl *Member) RemoveMember(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.service.Remove(projectID, userID, c.MustGet("user_id").(uint), c.MustGet("role").(string)); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// userIDParam parses :user_id and writes the error response itself when it is invalid
+func userIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "Invalid user id")
+		return 0, false
+	}
+	return uint(id), true
+}
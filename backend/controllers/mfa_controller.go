@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFA struct {
+	service *services.MFA
+	logins  *services.Logins
+	tokens  *services.Tokens
+}
+
+func NewMFA(service *services.MFA, logins *services.Logins, tokens *services.Tokens) *MFA {
+	return &MFA{
+		service: service,
+		logins:  logins,
+		tokens:  tokens,
+	}
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required,max=100"`
+	Code     string `json:"code" binding:"required,max=20"`
+}
+
+type MFAEnrollLoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required,max=100"`
+}
+
+// Code is a code from the authenticator app or, where accepted, a recovery code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required,max=20"`
+}
+
+// Login completes a login that answered with mfa_required. When the login enrolled
+// a new authenticator, the response also carries the recovery codes, shown only once.
+func (ctrl *MFA) Login(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	user, recoveryCodes, retryAfter, err := ctrl.logins.SecondFactor(req.MFAToken, req.Code, c.ClientIP())
+	if err != nil {
+		if retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		respondError(c, err)
+		return
+	}
+
+	pair, err := ctrl.tokens.Issue(user)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	response := tokenResponse(pair)
+	if len(recoveryCodes) > 0 {
+		response["recovery_codes"] = recoveryCodes
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// EnrollLogin sets up an authenticator for a login with enrollment_required
+func (ctrl *MFA) EnrollLogin(c *gin.Context) {
+	var req MFAEnrollLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	enrollment, err := ctrl.service.EnrollForChallenge(req.MFAToken)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (ctrl *MFA) GetStatus(c *gin.Context) {
+	status, err := ctrl.service.Status(c.MustGet("user_id").(uint))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// Enroll starts setting up an authenticator app; Confirm turns it on
+func (ctrl *MFA) Enroll(c *gin.Context) {
+	enrollment, err := ctrl.service.StartEnrollment(c.MustGet("user_id").(uint))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (ctrl *MFA) Confirm(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	recoveryCodes, err := ctrl.service.ConfirmEnrollment(c.MustGet("user_id").(uint), req.Code)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+func (ctrl *MFA) Disable(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if err := ctrl.service.Disable(c.MustGet("user_id").(uint), req.Code); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *MFA) RegenerateRecoveryCodes(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	recoveryCodes, err := ctrl.service.RegenerateRecoveryCodes(c.MustGet("user_id").(uint), req.Code)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+// ResetUser removes a user's MFA, e.g. after they lost their phone
+func (ctrl *MFA) ResetUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "invalid user id")
+		return
+	}
+
+	if err := ctrl.service.Reset(uint(id), c.GetUint("org_id"), c.MustGet("user_id").(uint)); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
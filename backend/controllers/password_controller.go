@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repositories"
+	"backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Password struct {
+	service       *services.Passwords
+	organizations repositories.OrganizationRepository
+}
+
+func NewPassword(service *services.Passwords, organizations repositories.OrganizationRepository) *Password {
+	return &Password{
+		service:       service,
+		organizations: organizations,
+	}
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Organization string `json:"organization" binding:"omitempty,max=50"`
+	Username     string `json:"username" binding:"required,max=30"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required,max=100"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ChangePassword signs the user out everywhere, including the current session
+func (ctrl *Password) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	retryAfter, err := ctrl.service.Change(c.MustGet("user_id").(uint), req.CurrentPassword, req.NewPassword, c.ClientIP())
+	if err != nil {
+		if retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ForgotPassword always answers the same way so it cannot be used to find accounts
+func (ctrl *Password) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if req.Organization == "" {
+		req.Organization = models.DefaultOrganizationSlug
+	}
+	if organization, err := ctrl.organizations.GetBySlug(req.Organization); err == nil {
+		go ctrl.service.RequestReset(organization.ID, req.Username)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists and has an email address, a reset link has been sent"})
+}
+
+func (ctrl *Password) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if err := ctrl.service.Reset(req.Token, req.NewPassword); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AdminResetPassword returns the reset link so it can be handed over when it could not be mailed
+func (ctrl *Password) AdminResetPassword(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "invalid user id")
+		return
+	}
+
+	link, mailed, err := ctrl.service.AdminReset(uint(id), c.GetUint("org_id"), c.MustGet("user_id").(uint))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"reset_url": link, "mailed": mailed})
+}
@@ -0,0 +1,262 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/microcosm-cc/bluemonday"
+)
+
+type Project struct {
+	service      *services.Project
+	strictPolicy *bluemonday.Policy
+	ugcPolicy    *bluemonday.Policy
+}
+
+func NewProject(service *services.Project) *Project {
+	return &Project{
+		service:      service,
+		strictPolicy: bluemonday.StrictPolicy(),
+		ugcPolicy:    bluemonday.UGCPolicy(),
+	}
+}
+
+type CreateProjectRequest struct {
+	Name        string `json:"name" binding:"required,min=3,max=100"`
+	Description string `json:"description" binding:"max=500"`
+	IsTemplate  bool   `json:"is_template"`
+}
+
+type UpdateProjectRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=3,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+	IsTemplate  *bool   `json:"is_template"`
+}
+
+type CloneProjectRequest struct {
+	Name            string  `json:"name" binding:"required,min=3,max=100"`
+	Description     *string `json:"description" binding:"omitempty,max=500"`
+	IncludeMembers  bool    `json:"include_members"`
+	IncludeDrawings bool    `json:"include_drawings"`
+}
+
+type ArchiveProjectRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+type UnarchiveProjectRequest struct {
+	Reason string `json:"reason" binding:"required,min=3,max=500"`
+}
+
+// GetProjects lists live projects: all of the organization's for admins, the caller's own
+// for everyone else. Archived ones are included with ?include_archived=true
+func (ctrl *Project) GetProjects(c *gin.Context) {
+	projects, err := ctrl.service.List(c.GetUint("org_id"), c.MustGet("user_id").(uint), c.GetString("role"), c.Query("include_archived") == "true")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, projects)
+}
+
+func (ctrl *Project) GetTemplates(c *gin.Context) {
+	projects, err := ctrl.service.ListTemplates(c.GetUint("org_id"), c.MustGet("user_id").(uint), c.GetString("role"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, projects)
+}
+
+// GetMyProjects lists the projects the caller is a member of, with their role in each
+func (ctrl *Project) GetMyProjects(c *gin.Context) {
+	projects, err := ctrl.service.ListForUser(c.MustGet("user_id").(uint), c.Query("include_archived") == "true")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, projects)
+}
+
+func (ctrl *Project) GetProject(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	project, err := ctrl.service.Get(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// GetProjectStats serves the dashboard aggregates for one project
+func (ctrl *Project) GetProjectStats(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	stats, err := ctrl.service.Stats(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+func (ctrl *Project) CreateProject(c *gin.Context) {
+	var req CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	project := models.Project{
+		OrganizationID: c.GetUint("org_id"),
+		Name:           ctrl.strictPolicy.Sanitize(req.Name),
+		Description:    ctrl.ugcPolicy.Sanitize(req.Description),
+		IsTemplate:     req.IsTemplate,
+	}
+
+	userID := c.MustGet("user_id").(uint)
+	role := models.UserRole(c.MustGet("role").(string))
+	if err := ctrl.service.Create(&project, userID, role); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+func (ctrl *Project) UpdateProject(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	changes := services.ProjectChanges{}
+	if req.Name != nil {
+		name := ctrl.strictPolicy.Sanitize(*req.Name)
+		changes.Name = &name
+	}
+	if req.Description != nil {
+		description := ctrl.ugcPolicy.Sanitize(*req.Description)
+		changes.Description = &description
+	}
+	changes.IsTemplate = req.IsTemplate
+
+	project, err := ctrl.service.Update(id, changes)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// CloneProject copies a project (typically a template) into a new one, optionally with members and drawings
+func (ctrl *Project) CloneProject(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req CloneProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	opts := services.CloneOptions{
+		Name:            ctrl.strictPolicy.Sanitize(req.Name),
+		IncludeMembers:  req.IncludeMembers,
+		IncludeDrawings: req.IncludeDrawings,
+	}
+	if req.Description != nil {
+		description := ctrl.ugcPolicy.Sanitize(*req.Description)
+		opts.Description = &description
+	}
+
+	userID := c.MustGet("user_id").(uint)
+	role := models.UserRole(c.MustGet("role").(string))
+	project, err := ctrl.service.Clone(id, opts, userID, role)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, project)
+}
+
+func (ctrl *Project) DeleteProject(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.service.Delete(id); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *Project) ArchiveProject(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req ArchiveProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	project, err := ctrl.service.Archive(id, ctrl.strictPolicy.Sanitize(req.Reason), c.MustGet("user_id").(uint))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+func (ctrl *Project) UnarchiveProject(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req UnarchiveProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	project, err := ctrl.service.Unarchive(id, ctrl.strictPolicy.Sanitize(req.Reason), c.MustGet("user_id").(uint))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// projectIDParam parses :id and writes the error response itself when it is invalid
+func projectIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "Invalid project id")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ServiceAccount struct {
+	service *services.APIKeys
+}
+
+func NewServiceAccount(service *services.APIKeys) *ServiceAccount {
+	return &ServiceAccount{service: service}
+}
+
+type CreateServiceAccountRequest struct {
+	Username string          `json:"username" binding:"required,alphanum,min=3,max=30"`
+	Role     models.UserRole `json:"role" binding:"required,oneof=admin drafter shift_lead final_qc"`
+}
+
+// ExpiresInDays defaults to services.DefaultAPIKeyTTL
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,max=20,dive,max=50"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+func (ctrl *ServiceAccount) CreateServiceAccount(c *gin.Context) {
+	var req CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	user, err := ctrl.service.CreateServiceAccount(c.GetUint("org_id"), c.MustGet("user_id").(uint), req.Username, req.Role)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+func (ctrl *ServiceAccount) GetServiceAccounts(c *gin.Context) {
+	users, err := ctrl.service.ListServiceAccounts(c.GetUint("org_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// CreateAPIKey returns the key itself only in this response
+func (ctrl *ServiceAccount) CreateAPIKey(c *gin.Context) {
+	accountID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	key, raw, err := ctrl.service.CreateKey(accountID, c.GetUint("org_id"), c.MustGet("user_id").(uint), req.Name, req.Scopes, ttl)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"api_key": key, "key": raw})
+}
+
+func (ctrl *ServiceAccount) GetAPIKeys(c *gin.Context) {
+	accountID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	keys, err := ctrl.service.ListKeys(accountID, c.GetUint("org_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+func (ctrl *ServiceAccount) RevokeAPIKey(c *gin.Context) {
+	accountID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	keyID, err := strconv.ParseUint(c.Param("key_id"), 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "invalid key id")
+		return
+	}
+
+	if err := ctrl.service.RevokeKey(accountID, uint(keyID), c.GetUint("org_id"), c.MustGet("user_id").(uint)); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
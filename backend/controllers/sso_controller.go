@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"backend/auth"
+	"backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ssoBindingCookie holds the value tying a login in progress to the browser that started it
+	ssoBindingCookie = "sso_binding"
+	ssoCookiePath    = "/api/v1/auth/oidc"
+)
+
+type SSO struct {
+	service      *services.SSO
+	secureCookie bool
+}
+
+// NewSSO marks the binding cookie Secure when secureCookie is set, i.e. when served over HTTPS
+func NewSSO(service *services.SSO, secureCookie bool) *SSO {
+	return &SSO{service: service, secureCookie: secureCookie}
+}
+
+type SSOCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// Login redirects the browser to the identity provider
+func (ctrl *SSO) Login(c *gin.Context) {
+	url, binding, err := ctrl.service.Start(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	ctrl.setBindingCookie(c, binding, int(auth.OIDCLoginTTL.Seconds()))
+	c.Redirect(http.StatusFound, url)
+}
+
+// Callback is called by the frontend with the code and state the provider redirected back with
+func (ctrl *SSO) Callback(c *gin.Context) {
+	var req SSOCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	binding, _ := c.Cookie(ssoBindingCookie)
+	ctrl.setBindingCookie(c, "", -1)
+	pair, err := ctrl.service.Callback(c.Request.Context(), req.State, req.Code, binding)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	respondTokens(c, pair)
+}
+
+// setBindingCookie keeps the binding out of scripts and off cross-site requests; a negative maxAge clears it
+func (ctrl *SSO) setBindingCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoBindingCookie, value, maxAge, ssoCookiePath, "", ctrl.secureCookie, true)
+}
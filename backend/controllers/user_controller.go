@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repositories"
+	"backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type User struct {
+	tokens   *services.Tokens
+	accounts *services.Accounts
+	logins   *services.Logins
+	users    *services.Users
+}
+
+func NewUser(tokens *services.Tokens, accounts *services.Accounts, logins *services.Logins, users *services.Users) *User {
+	return &User{
+		tokens:   tokens,
+		accounts: accounts,
+		logins:   logins,
+		users:    users,
+	}
+}
+
+// UserSearchRequest filters GET /users; q matches part of the username or email
+type UserSearchRequest struct {
+	Query  string            `form:"q" binding:"max=100"`
+	Role   models.UserRole   `form:"role" binding:"omitempty,oneof=admin drafter shift_lead final_qc"`
+	Status models.UserStatus `form:"status" binding:"omitempty,oneof=active pending deactivated"`
+	Limit  int               `form:"limit" binding:"omitempty,min=1,max=200"`
+	Offset int               `form:"offset" binding:"omitempty,min=0"`
+}
+
+type ChangeRoleRequest struct {
+	Role models.UserRole `json:"role" binding:"required,oneof=admin drafter shift_lead final_qc"`
+}
+
+// GetUsers lists the organization's users a page at a time; X-Total-Count has the number of matches
+func (ctrl *User) GetUsers(c *gin.Context) {
+	var req UserSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	users, total, err := ctrl.users.Search(c.GetUint("org_id"), repositories.UserFilter{
+		Query:  req.Query,
+		Role:   req.Role,
+		Status: req.Status,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	c.JSON(http.StatusOK, users)
+}
+
+func (ctrl *User) ChangeRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "invalid user id")
+		return
+	}
+	var req ChangeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	user, err := ctrl.users.ChangeRole(uint(id), c.GetUint("org_id"), c.MustGet("user_id").(uint), req.Role)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// DeactivateUser signs a leaver out for good and releases the drawings they had claimed
+func (ctrl *User) DeactivateUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "invalid user id")
+		return
+	}
+
+	user, err := ctrl.users.Deactivate(uint(id), c.GetUint("org_id"), c.MustGet("user_id").(uint))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (ctrl *User) ReactivateUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "invalid user id")
+		return
+	}
+
+	user, err := ctrl.users.Reactivate(uint(id), c.GetUint("org_id"), c.MustGet("user_id").(uint))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// GetPendingUsers lists self-registered accounts waiting for approval
+func (ctrl *User) GetPendingUsers(c *gin.Context) {
+	users, err := ctrl.accounts.ListPending(c.GetUint("org_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+func (ctrl *User) ApproveUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "invalid user id")
+		return
+	}
+
+	user, err := ctrl.accounts.Approve(uint(id), c.GetUint("org_id"), c.MustGet("user_id").(uint))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// RevokeSessions signs the user out of every session
+func (ctrl *User) RevokeSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "invalid user id")
+		return
+	}
+
+	if err := ctrl.tokens.RevokeAll(uint(id), c.MustGet("user_id").(uint)); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// UnlockUser lifts a lockout after too many failed logins
+func (ctrl *User) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidID, "invalid user id")
+		return
+	}
+
+	if err := ctrl.logins.Unlock(uint(id), c.GetUint("org_id"), c.MustGet("user_id").(uint)); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/fr"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	de_translations "github.com/go-playground/validator/v10/translations/de"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	fr_translations "github.com/go-playground/validator/v10/translations/fr"
+)
+
+const CodeValidationFailed = "VALIDATION_FAILED"
+
+// FieldError describes one failing validation rule on one request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+type translationRegistrar func(v *validator.Validate, trans ut.Translator) error
+
+// Message templates come from the validator's per-locale translation packages.
+// Adding a language means adding its locale and registrar here.
+var supportedLocales = []struct {
+	locale   locales.Translator
+	register translationRegistrar
+}{
+	{en.New(), en_translations.RegisterDefaultTranslations},
+	{de.New(), de_translations.RegisterDefaultTranslations},
+	{fr.New(), fr_translations.RegisterDefaultTranslations},
+}
+
+var translators *ut.UniversalTranslator
+
+// InitValidator makes gin's validator report JSON field names and registers
+// translated messages for every supported locale. English is the fallback.
+func InitValidator() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		log.Fatal("Unexpected validator engine")
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	fallback := supportedLocales[0].locale
+	all := make([]locales.Translator, len(supportedLocales))
+	for i, l := range supportedLocales {
+		all[i] = l.locale
+	}
+	translators = ut.New(fallback, all...)
+
+	for _, l := range supportedLocales {
+		trans, _ := translators.GetTranslator(l.locale.Locale())
+		if err := l.register(v, trans); err != nil {
+			log.Fatalf("Failed to register %s validation messages: %v", l.locale.Locale(), err)
+		}
+	}
+}
+
+// respondBindError reports every failing field of a request body or query,
+// translated according to the Accept-Language header.
+func respondBindError(c *gin.Context, err error) {
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		trans := requestTranslator(c)
+		fields := make([]FieldError, len(ve))
+		for i, fe := range ve {
+			fields[i] = FieldError{
+				Field:   fieldPath(fe),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: translate(fe, trans),
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error":  fields[0].Message,
+			"code":   CodeValidationFailed,
+			"fields": fields,
+		})
+		return
+	}
+
+	// Malformed JSON or a value of the wrong type never reaches the validator
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid value for " + typeErr.Field,
+			"code":  CodeValidationFailed,
+			"fields": []FieldError{{
+				Field:   typeErr.Field,
+				Rule:    "type",
+				Param:   typeErr.Type.String(),
+				Message: typeErr.Field + " must be of type " + typeErr.Type.String(),
+			}},
+		})
+		return
+	}
+
+	abortWithError(c, http.StatusBadRequest, CodeInvalidInput, "Invalid input data")
+}
+
+func requestTranslator(c *gin.Context) ut.Translator {
+	if translators == nil {
+		return nil
+	}
+	var preferred []string
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if tag != "" {
+			// "en-GB" -> "en_GB", then the bare language as a fallback
+			preferred = append(preferred, strings.ReplaceAll(tag, "-", "_"), strings.SplitN(tag, "-", 2)[0])
+		}
+	}
+	trans, _ := translators.FindTranslator(preferred...)
+	return trans
+}
+
+func translate(fe validator.FieldError, trans ut.Translator) string {
+	if trans == nil {
+		return fe.Error()
+	}
+	return fe.Translate(trans)
+}
+
+// fieldPath drops the request struct name: "BatchActionRequest.items[0].id" -> "items[0].id"
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
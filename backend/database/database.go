@@ -2,6 +2,7 @@ package database
 
 import (
 	"backend/models"
+	"fmt"
 	"log"
 
 	"gorm.io/driver/postgres"
@@ -19,11 +20,61 @@ func InitDB(dbURL string) {
 
 	log.Println("Database connection established")
 
+	backfillStageChangedAt := !DB.Migrator().HasColumn(&models.Drawing{}, "stage_changed_at")
+
+	// Users and projects that predate organizations move into the default organization
+	if err := DB.AutoMigrate(&models.Organization{}); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+	defaultOrg := models.Organization{Name: "Default", Slug: models.DefaultOrganizationSlug}
+	if err := DB.Where("slug = ?", defaultOrg.Slug).FirstOrCreate(&defaultOrg).Error; err != nil {
+		log.Fatalf("Failed to create default organization: %v", err)
+	}
+	for _, table := range []string{"users", "projects"} {
+		if !DB.Migrator().HasTable(table) || DB.Migrator().HasColumn(table, "organization_id") {
+			continue
+		}
+		err := DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN organization_id bigint NOT NULL DEFAULT %d", table, defaultOrg.ID)).Error
+		if err == nil {
+			err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN organization_id DROP DEFAULT", table)).Error
+		}
+		if err != nil {
+			log.Fatalf("Failed to add organization_id to %s: %v", table, err)
+		}
+	}
+
 	// Run migrations: On Production will comment this out.
-	err = DB.AutoMigrate(&models.Project{}, &models.ProjectMember{}, &models.User{}, &models.Drawing{}, &models.WorkflowLog{})
+	err = DB.AutoMigrate(&models.Project{}, &models.ProjectMember{}, &models.User{}, &models.Drawing{}, &models.WorkflowLog{}, &models.RefreshToken{}, &models.Invitation{}, &models.InvitationMembership{}, &models.APIKey{}, &models.PasswordHistory{}, &models.PasswordResetToken{}, &models.TOTPCredential{}, &models.RecoveryCode{})
 	if err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Project names used to be unique across deleted projects too, and
+	// usernames and project names used to be unique across organizations
+	legacyIndexes := map[interface{}][]string{
+		&models.Project{}: {"idx_projects_name", "idx_projects_name_active"},
+		&models.User{}:    {"idx_users_username"},
+	}
+	for model, indexes := range legacyIndexes {
+		for _, index := range indexes {
+			if !DB.Migrator().HasIndex(model, index) {
+				continue
+			}
+			if err := DB.Migrator().DropIndex(model, index); err != nil {
+				log.Fatalf("Failed to drop legacy index %s: %v", index, err)
+			}
+		}
+	}
+
+	// Existing drawings get the time of their last stage change instead of the migration time
+	if backfillStageChangedAt {
+		err := DB.Exec(`UPDATE drawings SET stage_changed_at = COALESCE(
+			(SELECT MAX(timestamp) FROM workflow_logs WHERE workflow_logs.drawing_id = drawings.id AND from_stage <> to_stage),
+			created_at)`).Error
+		if err != nil {
+			log.Fatalf("Failed to backfill stage_changed_at: %v", err)
+		}
+	}
+
 	log.Println("Database migrations completed")
 }
@@ -0,0 +1,22 @@
+package database
+
+import (
+	"context"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var Redis *redis.Client
+
+func InitRedis(redisURL string) {
+	Redis = redis.NewClient(&redis.Options{
+		Addr: redisURL,
+	})
+
+	if _, err := Redis.Ping(context.Background()).Result(); err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+
+	log.Println("Redis connection established")
+}
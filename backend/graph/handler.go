@@ -0,0 +1,83 @@
+package graph
+
+import (
+	_ "embed"
+	"io"
+	"net/http"
+	"strings"
+
+	"backend/models"
+	"backend/realtime"
+	"backend/repositories"
+	"backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+type Handler struct {
+	schema *graphql.Schema
+}
+
+func NewHandler(projects repositories.ProjectRepository, drawings repositories.DrawingRepository, users repositories.UserRepository, service *services.Drawing, access *services.Access, membership *services.Membership, broadcaster realtime.Broadcaster) *Handler {
+	resolver := &Resolver{
+		projects:    projects,
+		drawings:    drawings,
+		users:       users,
+		service:     service,
+		access:      access,
+		membership:  membership,
+		broadcaster: broadcaster,
+	}
+	return &Handler{
+		schema: graphql.MustParseSchema(schemaSDL, resolver, graphql.MaxDepth(8)),
+	}
+}
+
+type request struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Serve executes queries and mutations as plain JSON. Subscriptions are served
+// over Server-Sent Events when the client sends "Accept: text/event-stream".
+func (h *Handler) Serve(c *gin.Context) {
+	var req request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query is required", "code": "INVALID_INPUT"})
+		return
+	}
+
+	key, _ := c.Get("api_key")
+	apiKey, _ := key.(*models.APIKey)
+	ctx := withViewer(c.Request.Context(), c.MustGet("user_id").(uint), c.GetUint("org_id"), c.MustGet("role").(string), apiKey)
+
+	if !strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		c.JSON(http.StatusOK, h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+		return
+	}
+
+	responses, err := h.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_INPUT"})
+		return
+	}
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case resp, ok := <-responses:
+			if !ok {
+				c.SSEvent("complete", "")
+				return false
+			}
+			c.SSEvent("next", resp)
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
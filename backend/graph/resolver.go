@@ -0,0 +1,320 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"backend/auth"
+	"backend/models"
+	"backend/realtime"
+	"backend/repositories"
+	"backend/services"
+
+	"github.com/graph-gophers/graphql-go"
+	"gorm.io/gorm"
+)
+
+var (
+	errPermissionDenied = &services.Error{Kind: services.KindForbidden, Code: "PERMISSION_DENIED", Message: "Permission denied"}
+	errInvalidID        = &services.Error{Kind: services.KindInvalid, Code: "INVALID_ID", Message: "Invalid id"}
+	errScopeNotGranted  = &services.Error{Kind: services.KindForbidden, Code: "SCOPE_NOT_GRANTED", Message: "API key scope does not allow this action"}
+)
+
+type viewerKey struct{}
+
+type viewer struct {
+	userID uint
+	orgID  uint
+	role   string
+	apiKey *models.APIKey // nil unless the request was made with an API key
+}
+
+func withViewer(ctx context.Context, userID uint, orgID uint, role string, apiKey *models.APIKey) context.Context {
+	return context.WithValue(ctx, viewerKey{}, viewer{userID: userID, orgID: orgID, role: role, apiKey: apiKey})
+}
+
+// checkScope limits API keys to the scopes the matching REST route would require
+func (v viewer) checkScope(obj string, act string) error {
+	if v.apiKey != nil && !services.ScopesAllow(v.apiKey.Scopes, obj, act) {
+		return errScopeNotGranted
+	}
+	return nil
+}
+
+// authorize applies the same Casbin policy and API key scopes as the REST routes to a resolver
+func authorize(ctx context.Context, obj string, act string) (viewer, error) {
+	v, ok := ctx.Value(viewerKey{}).(viewer)
+	if !ok {
+		return viewer{}, errPermissionDenied
+	}
+	allowed, err := auth.Enforce(v.role, v.orgID, obj, act)
+	if err != nil {
+		return viewer{}, services.Internal(err)
+	}
+	if !allowed {
+		return viewer{}, errPermissionDenied
+	}
+	if err := v.checkScope(obj, act); err != nil {
+		return viewer{}, err
+	}
+	return v, nil
+}
+
+func parseID(id graphql.ID) (uint, error) {
+	n, err := strconv.ParseUint(string(id), 10, 32)
+	if err != nil {
+		return 0, errInvalidID
+	}
+	return uint(n), nil
+}
+
+func formatID(id uint) graphql.ID {
+	return graphql.ID(strconv.FormatUint(uint64(id), 10))
+}
+
+// Resolver is the root resolver for queries, mutations and subscriptions
+type Resolver struct {
+	projects    repositories.ProjectRepository
+	drawings    repositories.DrawingRepository
+	users       repositories.UserRepository
+	service     *services.Drawing
+	access      *services.Access
+	membership  *services.Membership
+	broadcaster realtime.Broadcaster
+}
+
+func (r *Resolver) Me(ctx context.Context) (*userResolver, error) {
+	v, ok := ctx.Value(viewerKey{}).(viewer)
+	if !ok {
+		return nil, errPermissionDenied
+	}
+	return r.user(ctx, v.userID)
+}
+
+func (r *Resolver) Projects(ctx context.Context) ([]*projectResolver, error) {
+	v, err := authorize(ctx, "projects", "view")
+	if err != nil {
+		return nil, err
+	}
+	// Like GET /projects: admins see the organization's projects, everyone else their own
+	var projects []models.Project
+	if v.role == string(models.RoleAdmin) {
+		projects, err = r.projects.List(v.orgID, false)
+	} else {
+		var memberships []repositories.MemberProject
+		memberships, err = r.projects.ListForUser(v.userID, false)
+		for _, membership := range memberships {
+			projects = append(projects, membership.Project)
+		}
+	}
+	if err != nil {
+		return nil, services.Internal(err)
+	}
+	out := make([]*projectResolver, len(projects))
+	for i := range projects {
+		out[i] = &projectResolver{root: r, p: projects[i]}
+	}
+	return out, nil
+}
+
+func (r *Resolver) Project(ctx context.Context, args struct{ ID graphql.ID }) (*projectResolver, error) {
+	v, err := authorize(ctx, "projects", "view")
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.access.Authorize(id, v.userID, v.role, "projects", "view"); err != nil {
+		return nil, err
+	}
+	project, err := r.projects.Get(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, services.Internal(err)
+	}
+	return &projectResolver{root: r, p: *project}, nil
+}
+
+func (r *Resolver) Drawings(ctx context.Context, args struct{ ProjectID *graphql.ID }) ([]*drawingResolver, error) {
+	v, err := authorize(ctx, "drawings", "view")
+	if err != nil {
+		return nil, err
+	}
+	var projectID uint
+	if args.ProjectID != nil {
+		id, err := parseID(*args.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		projectID = id
+	}
+	return r.drawingList(v, projectID)
+}
+
+func (r *Resolver) Drawing(ctx context.Context, args struct{ ID graphql.ID }) (*drawingResolver, error) {
+	v, err := authorize(ctx, "drawings", "view")
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	drawing, err := r.service.Get(id, v.userID, v.role)
+	if errors.Is(err, services.ErrDrawingNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &drawingResolver{root: r, d: *drawing}, nil
+}
+
+func (r *Resolver) Users(ctx context.Context) ([]*userResolver, error) {
+	v, err := authorize(ctx, "users", "view")
+	if err != nil {
+		return nil, err
+	}
+	users, err := r.users.List(v.orgID)
+	if err != nil {
+		return nil, services.Internal(err)
+	}
+	out := make([]*userResolver, len(users))
+	for i := range users {
+		out[i] = &userResolver{u: users[i]}
+	}
+	return out, nil
+}
+
+func (r *Resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	if _, err := authorize(ctx, "users", "view"); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return r.optionalUser(ctx, id)
+}
+
+func (r *Resolver) ClaimDrawing(ctx context.Context, args struct{ ID graphql.ID }) (*drawingResolver, error) {
+	return r.workflowAction(ctx, args.ID, models.ActionClaim)
+}
+
+func (r *Resolver) SubmitDrawing(ctx context.Context, args struct{ ID graphql.ID }) (*drawingResolver, error) {
+	return r.workflowAction(ctx, args.ID, models.ActionSubmit)
+}
+
+func (r *Resolver) ReleaseDrawing(ctx context.Context, args struct{ ID graphql.ID }) (*drawingResolver, error) {
+	return r.workflowAction(ctx, args.ID, models.ActionRelease)
+}
+
+func (r *Resolver) RejectDrawing(ctx context.Context, args struct{ ID graphql.ID }) (*drawingResolver, error) {
+	return r.workflowAction(ctx, args.ID, models.ActionReject)
+}
+
+// workflowAction is authorized by the service against the caller's role in the drawing's project
+func (r *Resolver) workflowAction(ctx context.Context, rawID graphql.ID, action models.Action) (*drawingResolver, error) {
+	v, ok := ctx.Value(viewerKey{}).(viewer)
+	if !ok {
+		return nil, errPermissionDenied
+	}
+	if err := v.checkScope("drawings", string(action)); err != nil {
+		return nil, err
+	}
+	id, err := parseID(rawID)
+	if err != nil {
+		return nil, err
+	}
+	drawing, err := r.service.ProcessWorkflowAction(id, v.userID, v.role, action)
+	if err != nil {
+		return nil, err
+	}
+	return &drawingResolver{root: r, d: *drawing}, nil
+}
+
+func (r *Resolver) ProjectEvents(ctx context.Context, args struct{ ProjectID graphql.ID }) (<-chan *eventResolver, error) {
+	v, err := authorize(ctx, "drawings", "view")
+	if err != nil {
+		return nil, err
+	}
+	projectID, err := parseID(args.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.access.Authorize(projectID, v.userID, v.role, "drawings", "view"); err != nil {
+		return nil, err
+	}
+
+	clientChan := r.broadcaster.Subscribe(projectID)
+	out := make(chan *eventResolver)
+
+	go func() {
+		defer close(out)
+		defer r.broadcaster.Unsubscribe(projectID, clientChan)
+		for {
+			select {
+			case msg, ok := <-clientChan:
+				if !ok {
+					return
+				}
+				e, err := decodeEvent(msg)
+				if err != nil {
+					continue
+				}
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// drawingList applies the same project membership rules as GET /drawings
+func (r *Resolver) drawingList(v viewer, projectID uint) ([]*drawingResolver, error) {
+	drawings, err := r.service.List(v.orgID, projectID, v.userID, v.role)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*drawingResolver, len(drawings))
+	for i := range drawings {
+		out[i] = &drawingResolver{root: r, d: drawings[i]}
+	}
+	return out, nil
+}
+
+// user loads a user of the viewer's organization; users of other organizations are reported as missing
+func (r *Resolver) user(ctx context.Context, id uint) (*userResolver, error) {
+	v, ok := ctx.Value(viewerKey{}).(viewer)
+	if !ok {
+		return nil, errPermissionDenied
+	}
+	u, err := r.users.Get(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && u.OrganizationID != v.orgID) {
+		return nil, services.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, services.Internal(err)
+	}
+	return &userResolver{u: *u}, nil
+}
+
+// optionalUser is user for nullable fields, which resolve to null when the user is missing
+func (r *Resolver) optionalUser(ctx context.Context, id uint) (*userResolver, error) {
+	u, err := r.user(ctx, id)
+	if errors.Is(err, services.ErrUserNotFound) {
+		return nil, nil
+	}
+	return u, err
+}
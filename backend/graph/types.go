@@ -0,0 +1,164 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"backend/models"
+	"backend/services"
+
+	"github.com/graph-gophers/graphql-go"
+	"gorm.io/gorm"
+)
+
+type projectResolver struct {
+	root *Resolver
+	p    models.Project
+}
+
+func (p *projectResolver) ID() graphql.ID          { return formatID(p.p.ID) }
+func (p *projectResolver) Name() string            { return p.p.Name }
+func (p *projectResolver) Description() string     { return p.p.Description }
+func (p *projectResolver) CreatedAt() graphql.Time { return graphql.Time{Time: p.p.CreatedAt} }
+
+func (p *projectResolver) Drawings(ctx context.Context) ([]*drawingResolver, error) {
+	v, err := authorize(ctx, "drawings", "view")
+	if err != nil {
+		return nil, err
+	}
+	return p.root.drawingList(v, p.p.ID)
+}
+
+// Members follows GET /projects/:id/members: only admins and project owners see them
+func (p *projectResolver) Members(ctx context.Context) ([]*memberResolver, error) {
+	v, err := authorize(ctx, "projects", "view")
+	if err != nil {
+		return nil, err
+	}
+	if err := v.checkScope("members", "view"); err != nil {
+		return nil, err
+	}
+	members, err := p.root.membership.List(p.p.ID, v.userID, v.role)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*memberResolver, len(members))
+	for i := range members {
+		out[i] = &memberResolver{root: p.root, m: members[i]}
+	}
+	return out, nil
+}
+
+type memberResolver struct {
+	root *Resolver
+	m    models.ProjectMember
+}
+
+func (m *memberResolver) Role() string           { return string(m.m.Role) }
+func (m *memberResolver) JoinedAt() graphql.Time { return graphql.Time{Time: m.m.JoinedAt} }
+
+func (m *memberResolver) User(ctx context.Context) (*userResolver, error) {
+	return m.root.user(ctx, m.m.UserID)
+}
+
+type drawingResolver struct {
+	root *Resolver
+	d    models.Drawing
+}
+
+func (d *drawingResolver) ID() graphql.ID          { return formatID(d.d.ID) }
+func (d *drawingResolver) Title() string           { return d.d.Title }
+func (d *drawingResolver) Description() string     { return d.d.Description }
+func (d *drawingResolver) CurrentStage() string    { return string(d.d.CurrentStage) }
+func (d *drawingResolver) Revision() int32         { return int32(d.d.Revision) }
+func (d *drawingResolver) Version() int32          { return int32(d.d.Version) }
+func (d *drawingResolver) DrawingURL() string      { return d.d.DrawingURL }
+func (d *drawingResolver) CreatedAt() graphql.Time { return graphql.Time{Time: d.d.CreatedAt} }
+func (d *drawingResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: d.d.UpdatedAt} }
+
+func (d *drawingResolver) Project(ctx context.Context) (*projectResolver, error) {
+	if _, err := authorize(ctx, "projects", "view"); err != nil {
+		return nil, err
+	}
+	project, err := d.root.projects.Get(d.d.ProjectID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, services.Internal(err)
+	}
+	return &projectResolver{root: d.root, p: *project}, nil
+}
+
+func (d *drawingResolver) Author(ctx context.Context) (*userResolver, error) {
+	if d.d.AuthorID == 0 {
+		return nil, nil
+	}
+	return d.root.optionalUser(ctx, d.d.AuthorID)
+}
+
+func (d *drawingResolver) Assignee(ctx context.Context) (*userResolver, error) {
+	if d.d.AssigneeID == nil {
+		return nil, nil
+	}
+	return d.root.optionalUser(ctx, *d.d.AssigneeID)
+}
+
+func (d *drawingResolver) History(ctx context.Context, args struct{ Limit int32 }) ([]*workflowLogResolver, error) {
+	if _, err := authorize(ctx, "drawings", "view"); err != nil {
+		return nil, err
+	}
+	limit := int(args.Limit)
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	logs, err := d.root.drawings.GetWorkflowLogs(d.d.ID, limit)
+	if err != nil {
+		return nil, services.Internal(err)
+	}
+	out := make([]*workflowLogResolver, len(logs))
+	for i := range logs {
+		out[i] = &workflowLogResolver{l: logs[i]}
+	}
+	return out, nil
+}
+
+type workflowLogResolver struct {
+	l models.WorkflowLog
+}
+
+func (l *workflowLogResolver) ID() graphql.ID          { return formatID(l.l.ID) }
+func (l *workflowLogResolver) Action() string          { return l.l.Action }
+func (l *workflowLogResolver) FromStage() string       { return string(l.l.FromStage) }
+func (l *workflowLogResolver) ToStage() string         { return string(l.l.ToStage) }
+func (l *workflowLogResolver) Comment() string         { return l.l.Comment }
+func (l *workflowLogResolver) Actor() *userResolver    { return &userResolver{u: l.l.Actor} }
+func (l *workflowLogResolver) Timestamp() graphql.Time { return graphql.Time{Time: l.l.Timestamp} }
+
+type userResolver struct {
+	u models.User
+}
+
+func (u *userResolver) ID() graphql.ID   { return formatID(u.u.ID) }
+func (u *userResolver) Username() string { return u.u.Username }
+func (u *userResolver) Role() string     { return string(u.u.Role) }
+
+type eventResolver struct {
+	eventType string
+	payload   json.RawMessage
+}
+
+func (e *eventResolver) Type() string    { return e.eventType }
+func (e *eventResolver) Payload() string { return string(e.payload) }
+
+func decodeEvent(msg string) (*eventResolver, error) {
+	var event struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(msg), &event); err != nil {
+		return nil, err
+	}
+	return &eventResolver{eventType: event.Type, payload: event.Payload}, nil
+}
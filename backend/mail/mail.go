@@ -0,0 +1,64 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"backend/config"
+)
+
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
+
+// New returns an SMTP mailer when SMTP_HOST is set, and otherwise a mailer that only logs.
+// The log mailer is meant for development: reset links end up in the server log.
+func New(cfg *config.Config) Mailer {
+	if cfg.SMTPHost == "" {
+		log.Println("WARNING: SMTP_HOST is not set; mails are written to the log instead of being sent")
+		return LogMailer{}
+	}
+
+	addr := net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort)
+	var auth smtp.Auth
+	if cfg.SMTPUser != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUser, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	return &SMTPMailer{addr: addr, from: cfg.SMTPFrom, auth: auth}
+}
+
+type LogMailer struct{}
+
+func (LogMailer) Send(to string, subject string, body string) error {
+	log.Printf("Mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// SMTPMailer sends through an SMTP relay, using STARTTLS when the server offers it
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func (m *SMTPMailer) Send(to string, subject string, body string) error {
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+
+	msg := strings.Join([]string{
+		"From: " + m.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg))
+}
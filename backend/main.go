@@ -3,9 +3,11 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,9 +16,12 @@ import (
 	"backend/config"
 	"backend/controllers"
 	"backend/database"
+	"backend/graph"
+	"backend/mail"
 	"backend/middleware"
 	"backend/realtime"
 	"backend/repositories"
+	"backend/rpc"
 	"backend/services"
 	"backend/telemetry"
 
@@ -53,29 +58,157 @@ func main() {
 
 	// Initialize Database
 	database.InitDB(cfg.DBURL)
+	database.InitRedis(cfg.RedisURL)
+
+	// Initialize Repositories
+	userRepo := repositories.NewUserRepository(database.DB)
+	drawingRepo := repositories.NewDrawingRepository(database.DB)
+	projectRepo := repositories.NewProjectRepository(database.DB)
+	organizationRepo := repositories.NewOrganizationRepository(database.DB)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(database.DB)
+	invitationRepo := repositories.NewInvitationRepository(database.DB)
+	apiKeyRepo := repositories.NewAPIKeyRepository(database.DB)
+	passwordRepo := repositories.NewPasswordRepository(database.DB)
+	mfaRepo := repositories.NewMFARepository(database.DB)
 
 	// Initialize Services (Dependency Injection)
-	realtimeService := realtime.New(cfg)
+	realtimeService := realtime.New(database.Redis, projectRepo)
 	defer realtimeService.Shutdown()
 
 	auditService := audit.New(cfg)
 	defer auditService.Shutdown()
 
-	// Initialize Repositories
-	userRepo := repositories.NewUserRepository(database.DB)
-	drawingRepo := repositories.NewDrawingRepository(database.DB)
-
 	// Initialize Casbin
 	auth.InitCasbin(database.DB)
+	auth.AccessTokenTTL = cfg.AccessTokenTTL
+	if err := auth.InitKeys(cfg.JWTSecret, cfg.JWTKeyFiles, cfg.JWTActiveKeyID); err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+	auth.InitRevocations(database.Redis, 10000, 5*time.Second)
+
+	accessService := services.NewAccess(projectRepo, drawingRepo, userRepo, auditService)
+	drawingService := services.NewDrawing(drawingRepo, accessService, auditService, realtimeService)
+	projectService := services.NewProject(projectRepo, auditService, realtimeService)
+	tokenService := services.NewTokens(refreshTokenRepo, userRepo, auditService, realtimeService, cfg.RefreshTokenTTL)
+	membershipService := services.NewMembership(projectRepo, userRepo, auditService, realtimeService)
+	userService := services.NewUsers(projectRepo, tokenService, auditService, realtimeService)
+	apiKeyService := services.NewAPIKeys(apiKeyRepo, userRepo, auditService, realtimeService)
+
+	registrationMode := services.RegistrationMode(cfg.RegistrationMode)
+	switch registrationMode {
+	case services.RegistrationOpen, services.RegistrationApproval, services.RegistrationClosed:
+	default:
+		log.Fatalf("Invalid REGISTRATION_MODE %q (expected open, approval or closed)", cfg.RegistrationMode)
+	}
+	passwordPolicy, err := services.LoadPasswordPolicy(cfg.PasswordMinLength, cfg.PasswordHistory, cfg.PasswordBreachedFile)
+	if err != nil {
+		log.Fatalf("Failed to load password policy: %v", err)
+	}
+	accountService := services.NewAccounts(userRepo, invitationRepo, auditService, passwordPolicy, registrationMode)
+
+	// Passwords are checked locally first, then against LDAP when configured
+	authenticators := services.AuthenticatorChain{services.NewLocalAuthenticator(userRepo)}
+	if cfg.LDAPURL != "" {
+		ldapOrganization, err := organizationRepo.GetBySlug(cfg.LDAPOrganization)
+		if err != nil {
+			log.Fatalf("LDAP organization %q not found: %v", cfg.LDAPOrganization, err)
+		}
+		mapping, err := services.ParseGroupMapping(cfg.LDAPRoleMapping, cfg.LDAPProjectMapping, cfg.LDAPDefaultRole)
+		if err != nil {
+			log.Fatalf("Invalid LDAP group mapping: %v", err)
+		}
+		directory := auth.NewLDAPDirectory(auth.LDAPConfig{
+			URL:          cfg.LDAPURL,
+			StartTLS:     cfg.LDAPStartTLS,
+			BindDN:       cfg.LDAPBindDN,
+			BindPassword: cfg.LDAPBindPassword,
+			BaseDN:       cfg.LDAPBaseDN,
+			UserFilter:   cfg.LDAPUserFilter,
+			GroupFilter:  cfg.LDAPGroupFilter,
+			IDAttribute:  cfg.LDAPIDAttribute,
+			EmailAttr:    cfg.LDAPEmailAttribute,
+			GroupAttr:    cfg.LDAPGroupAttribute,
+		})
+		authenticators = append(authenticators, services.NewLDAPAuthenticator(directory, projectRepo, auditService, realtimeService, ldapOrganization.ID, mapping))
+	}
 
-	drawingService := services.NewDrawing(drawingRepo, auditService, realtimeService)
+	throttle := auth.NewLoginThrottle(database.Redis,
+		auth.ThrottleConfig{
+			FreeAttempts:    cfg.LoginFreeAttempts,
+			LockoutAttempts: cfg.LoginLockoutAttempts,
+			BaseDelay:       cfg.LoginBaseDelay,
+			LockoutDuration: cfg.LoginLockoutDuration,
+			Window:          cfg.LoginAttemptWindow,
+		},
+		auth.ThrottleConfig{
+			FreeAttempts:    cfg.LoginIPFreeAttempts,
+			LockoutAttempts: cfg.LoginIPLockoutAttempts,
+			BaseDelay:       cfg.LoginBaseDelay,
+			LockoutDuration: cfg.LoginLockoutDuration,
+			Window:          cfg.LoginAttemptWindow,
+		})
+	mfaRoles, err := services.ParseRoles(cfg.MFARequiredRoles)
+	if err != nil {
+		log.Fatalf("Invalid MFA_REQUIRED_ROLES: %v", err)
+	}
+	streamTickets := auth.NewStreamTickets(database.Redis, 30*time.Second)
+	mfaChallenges := auth.NewMFAChallenges(database.Redis, 5*time.Minute, 5)
+	mfaService, err := services.NewMFA(mfaRepo, mfaChallenges, tokenService, auditService, cfg.MFAIssuer, cfg.MFAEncryptionKey, mfaRoles)
+	if err != nil {
+		log.Fatalf("Failed to set up MFA: %v", err)
+	}
+	loginService := services.NewLogins(authenticators, throttle, mfaService, userRepo, auditService)
+	passwordService := services.NewPasswords(passwordRepo, loginService, tokenService, passwordPolicy, mail.New(cfg), auditService, cfg.PasswordResetURL, cfg.PasswordResetTTL)
+
+	// Single sign-on is optional
+	var ssoCtrl *controllers.SSO
+	if cfg.OIDCIssuer != "" {
+		provider, err := auth.NewOIDCProvider(context.Background(), auth.OIDCConfig{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       strings.Split(cfg.OIDCScopes, ","),
+			GroupsClaim:  cfg.OIDCGroupsClaim,
+		}, database.Redis)
+		if err != nil {
+			log.Fatalf("Failed to set up OIDC: %v", err)
+		}
+		ssoOrganization, err := organizationRepo.GetBySlug(cfg.OIDCOrganization)
+		if err != nil {
+			log.Fatalf("OIDC organization %q not found: %v", cfg.OIDCOrganization, err)
+		}
+		mapping, err := services.ParseGroupMapping(cfg.OIDCRoleMapping, cfg.OIDCProjectMapping, cfg.OIDCDefaultRole)
+		if err != nil {
+			log.Fatalf("Invalid OIDC group mapping: %v", err)
+		}
+		sso := services.NewSSO(provider, projectRepo, tokenService, auditService, realtimeService, ssoOrganization.ID, mapping)
+		ssoCtrl = controllers.NewSSO(sso, strings.HasPrefix(cfg.OIDCRedirectURL, "https://"))
+	}
 
 	// Initialize Controllers
-	authCtrl := controllers.NewAuth(userRepo)
-	drawingCtrl := controllers.NewDrawing(drawingRepo, drawingService)
-	eventCtrl := controllers.NewEvent(realtimeService)
+	controllers.InitValidator()
+	authCtrl := controllers.NewAuth(loginService, mfaService, organizationRepo, tokenService, accountService)
+	drawingCtrl := controllers.NewDrawing(drawingService)
+	projectCtrl := controllers.NewProject(projectService)
+	memberCtrl := controllers.NewMember(membershipService)
+	eventCtrl := controllers.NewEvent(realtimeService, streamTickets)
+	userCtrl := controllers.NewUser(tokenService, accountService, loginService, userService)
+	invitationCtrl := controllers.NewInvitation(accountService)
+	serviceAccountCtrl := controllers.NewServiceAccount(apiKeyService)
+	passwordCtrl := controllers.NewPassword(passwordService, organizationRepo)
+	mfaCtrl := controllers.NewMFA(mfaService, loginService, tokenService)
+	graphHandler := graph.NewHandler(projectRepo, drawingRepo, userRepo, drawingService, accessService, membershipService, realtimeService)
 
 	r := gin.Default()
+	// Client IPs feed login throttling, so forwarded addresses are only believed from known proxies
+	var trustedProxies []string
+	if cfg.TrustedProxies != "" {
+		trustedProxies = strings.Split(cfg.TrustedProxies, ",")
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// Middleware
 	r.Use(otelgin.Middleware("qc-system"))
@@ -87,33 +220,131 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "UP", "time": time.Now()})
 	})
 
+	// Public keys for verifying our tokens elsewhere
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"keys": auth.JWKS()})
+	})
+
 	// Public Routes
 	api := r.Group("/api/v1")
 	{
 		api.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 		api.POST("/register", authCtrl.Register)
 		api.POST("/login", authCtrl.Login)
+		api.POST("/login/mfa", mfaCtrl.Login)
+		api.POST("/login/mfa/enroll", mfaCtrl.EnrollLogin)
+		api.POST("/auth/refresh", authCtrl.Refresh)
+		api.POST("/password/forgot", passwordCtrl.ForgotPassword)
+		api.POST("/password/reset", passwordCtrl.ResetPassword)
+		if ssoCtrl != nil {
+			api.GET("/auth/oidc/login", ssoCtrl.Login)
+			api.POST("/auth/oidc/callback", ssoCtrl.Callback)
+		}
+
+		// SSE Events (Real-time), authenticated with a stream ticket or the usual headers
+		api.GET("/events", middleware.StreamAuthMiddleware(streamTickets, realtimeService, apiKeyService, tokenService), middleware.ProjectRBACMiddleware(accessService, "drawings", "view", middleware.ProjectFromQuery), eventCtrl.StreamEvents)
 	}
 
 	// Protected Routes
 	protected := api.Group("/")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(realtimeService, apiKeyService, tokenService))
 	{
-		// SSE Events (Real-time)
-		protected.GET("/events", eventCtrl.StreamEvents)
+		protected.POST("/auth/logout", authCtrl.Logout)
+		protected.POST("/me/password", passwordCtrl.ChangePassword)
+		protected.GET("/me/mfa", mfaCtrl.GetStatus)
+		protected.POST("/me/mfa/totp", mfaCtrl.Enroll)
+		protected.POST("/me/mfa/totp/confirm", mfaCtrl.Confirm)
+		protected.POST("/me/mfa/totp/disable", mfaCtrl.Disable)
+		protected.POST("/me/mfa/recovery-codes", mfaCtrl.RegenerateRecoveryCodes)
+
+		// Users
+		protected.GET("/users", middleware.RBACMiddleware("users", "view"), userCtrl.GetUsers)
+		protected.PATCH("/users/:id/role", middleware.RBACMiddleware("users", "change_role"), userCtrl.ChangeRole)
+		protected.POST("/users/:id/deactivate", middleware.RBACMiddleware("users", "deactivate"), userCtrl.DeactivateUser)
+		protected.POST("/users/:id/reactivate", middleware.RBACMiddleware("users", "deactivate"), userCtrl.ReactivateUser)
+		protected.GET("/users/pending", middleware.RBACMiddleware("users", "approve"), userCtrl.GetPendingUsers)
+		protected.POST("/users/:id/approve", middleware.RBACMiddleware("users", "approve"), userCtrl.ApproveUser)
+		protected.POST("/users/:id/sessions/revoke", middleware.RBACMiddleware("users", "revoke_sessions"), userCtrl.RevokeSessions)
+		protected.POST("/users/:id/mfa/reset", middleware.RBACMiddleware("users", "reset_mfa"), mfaCtrl.ResetUser)
+		protected.POST("/users/:id/unlock", middleware.RBACMiddleware("users", "unlock"), userCtrl.UnlockUser)
+		protected.POST("/users/:id/password-reset", middleware.RBACMiddleware("users", "reset_password"), passwordCtrl.AdminResetPassword)
+
+		// Invitations
+		protected.POST("/invitations", middleware.RBACMiddleware("users", "invite"), invitationCtrl.CreateInvitation)
+		protected.GET("/invitations", middleware.RBACMiddleware("users", "invite"), invitationCtrl.GetInvitations)
+		protected.DELETE("/invitations/:id", middleware.RBACMiddleware("users", "invite"), invitationCtrl.RevokeInvitation)
+
+		// Service accounts and their API keys
+		serviceAccounts := protected.Group("/service-accounts", middleware.RBACMiddleware("users", "manage_service_accounts"))
+		{
+			serviceAccounts.POST("", serviceAccountCtrl.CreateServiceAccount)
+			serviceAccounts.GET("", serviceAccountCtrl.GetServiceAccounts)
+			serviceAccounts.POST("/:user_id/keys", serviceAccountCtrl.CreateAPIKey)
+			serviceAccounts.GET("/:user_id/keys", serviceAccountCtrl.GetAPIKeys)
+			serviceAccounts.DELETE("/:user_id/keys/:key_id", serviceAccountCtrl.RevokeAPIKey)
+		}
+
+		// SSE Events (Real-time); browsers open the stream with a ticket from here
+		protected.POST("/events/ticket", middleware.ProjectRBACMiddleware(accessService, "drawings", "view", middleware.ProjectFromBody), eventCtrl.CreateTicket)
+
+		// GraphQL (authorization is applied per resolver)
+		protected.POST("/graphql", middleware.RequireScope("graphql", "use"), graphHandler.Serve)
+
+		// Projects
+		projects := protected.Group("/projects")
+		{
+			projects.GET("", middleware.RBACMiddleware("projects", "view"), projectCtrl.GetProjects)
+			projects.GET("/mine", middleware.RequireScope("projects", "view"), projectCtrl.GetMyProjects)
+			projects.GET("/templates", middleware.RBACMiddleware("projects", "view"), projectCtrl.GetTemplates)
+			// Routes on a single project resolve the caller's role in it, which also keeps organizations apart
+			projects.GET("/:id", middleware.ProjectRBACMiddleware(accessService, "projects", "view", middleware.ProjectFromParam), projectCtrl.GetProject)
+			projects.GET("/:id/stats", middleware.ProjectRBACMiddleware(accessService, "projects", "view", middleware.ProjectFromParam), projectCtrl.GetProjectStats)
+			projects.POST("", middleware.RBACMiddleware("projects", "create"), projectCtrl.CreateProject)
+			projects.PATCH("/:id", middleware.ProjectRBACMiddleware(accessService, "projects", "update", middleware.ProjectFromParam), projectCtrl.UpdateProject)
+			projects.DELETE("/:id", middleware.ProjectRBACMiddleware(accessService, "projects", "delete", middleware.ProjectFromParam), projectCtrl.DeleteProject)
+			projects.POST("/:id/archive", middleware.ProjectRBACMiddleware(accessService, "projects", "archive", middleware.ProjectFromParam), projectCtrl.ArchiveProject)
+			projects.POST("/:id/unarchive", middleware.ProjectRBACMiddleware(accessService, "projects", "unarchive", middleware.ProjectFromParam), projectCtrl.UnarchiveProject)
+			projects.POST("/:id/clone", middleware.ProjectRBACMiddleware(accessService, "projects", "create", middleware.ProjectFromParam), projectCtrl.CloneProject)
+
+			// Membership (admins and project owners, checked by the service)
+			projects.GET("/:id/members", middleware.RequireScope("members", "view"), memberCtrl.GetMembers)
+			projects.POST("/:id/members", middleware.RequireScope("members", "manage"), memberCtrl.AddMember)
+			projects.PATCH("/:id/members/:user_id", middleware.RequireScope("members", "manage"), memberCtrl.UpdateMember)
+			projects.DELETE("/:id/members/:user_id", middleware.RequireScope("members", "manage"), memberCtrl.RemoveMember)
+		}
 
 		// Drawings
 		drawings := protected.Group("/drawings")
 		{
+			// Permissions are checked against the caller's role in the drawing's project
+			fromDrawing := middleware.ProjectFromDrawing(accessService)
+			// Without project_id, only drawings from the caller's projects are listed
 			drawings.GET("", middleware.RBACMiddleware("drawings", "view"), drawingCtrl.GetDrawings)
-			drawings.POST("", middleware.RBACMiddleware("drawings", "create"), drawingCtrl.CreateDrawing)
-			drawings.POST("/:id/claim", middleware.RBACMiddleware("drawings", "claim"), drawingCtrl.ClaimDrawing)
-			drawings.POST("/:id/submit", middleware.RBACMiddleware("drawings", "submit"), drawingCtrl.SubmitDrawing)
-			drawings.POST("/:id/release", middleware.RBACMiddleware("drawings", "release"), drawingCtrl.ReleaseDrawing)
-			drawings.POST("/:id/reject", middleware.RBACMiddleware("drawings", "reject"), drawingCtrl.RejectDrawing)
+			drawings.POST("", middleware.ProjectRBACMiddleware(accessService, "drawings", "create", middleware.ProjectFromBody), drawingCtrl.CreateDrawing)
+			// Per-item permissions are enforced by the service, API key scopes by the controller
+			drawings.POST("/batch", drawingCtrl.BatchWorkflowActions)
+			drawings.POST("/:id/claim", middleware.ProjectRBACMiddleware(accessService, "drawings", "claim", fromDrawing), drawingCtrl.ClaimDrawing)
+			drawings.POST("/:id/submit", middleware.ProjectRBACMiddleware(accessService, "drawings", "submit", fromDrawing), drawingCtrl.SubmitDrawing)
+			drawings.POST("/:id/release", middleware.ProjectRBACMiddleware(accessService, "drawings", "release", fromDrawing), drawingCtrl.ReleaseDrawing)
+			drawings.POST("/:id/reject", middleware.ProjectRBACMiddleware(accessService, "drawings", "reject", fromDrawing), drawingCtrl.RejectDrawing)
 		}
 	}
 
+	// gRPC server (shares auth, RBAC and services with the REST API)
+	grpcServer := rpc.NewServer(rpc.NewDrawingServer(drawingService, accessService, realtimeService), realtimeService, tokenService)
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		log.Fatalf("Failed to listen for gRPC: %v", err)
+	}
+
+	go func() {
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatalf("grpc: %s\n", err)
+		}
+	}()
+
+	log.Printf("gRPC server started on port %s", cfg.GRPCPort)
+
 	// Server setup
 	srv := &http.Server{
 		Addr:    ":" + cfg.Port,
@@ -136,6 +367,18 @@ func main() {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+
+	// WatchProject streams only end when clients leave, so don't wait on them forever
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown:", err)
 	}
@@ -148,7 +391,8 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Total-Count, Retry-After")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -2,65 +2,158 @@ package middleware
 
 import (
 	"backend/auth"
+	"backend/models"
+	"backend/realtime"
+	"backend/services"
+	"context"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware validates the bearer token, rejects revoked ones and registers
+// the request with sessions so that revoking the token cancels it mid-flight.
+// Service accounts authenticate with an X-API-Key header instead; the key is
+// stored as "api_key" and its scopes are checked by the RBAC middlewares.
+// Tokens are only accepted in headers, so they stay out of URLs and access logs.
+// Deactivated users are rejected even while their token is valid, and role changes
+// apply at once since the role is read from the account rather than the token.
+func AuthMiddleware(sessions realtime.SessionTracker, apiKeys *services.APIKeys, tokens *services.Tokens) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		token := ""
-
-		if authHeader != "" {
-			parts := strings.Split(authHeader, " ")
-			if len(parts) == 2 && parts[0] == "Bearer" {
-				token = parts[1]
-			}
-		} else {
-			// Fallback to query param for SSE (EventSource doesn't support headers)
-			token = c.Query("token")
+		authenticate(c, sessions, apiKeys, tokens)
+	}
+}
+
+// StreamAuthMiddleware is AuthMiddleware for event streams. EventSource cannot send
+// headers, so it also takes a single-use ?ticket= from POST /events/ticket, which has
+// to match the ?project_id= of the stream.
+func StreamAuthMiddleware(tickets *auth.StreamTickets, sessions realtime.SessionTracker, apiKeys *services.APIKeys, tokens *services.Tokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := c.Query("ticket")
+		if raw == "" {
+			authenticate(c, sessions, apiKeys, tokens)
+			return
 		}
 
-		if token == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token is required"})
-			c.Abort()
+		ticket, err := tickets.Redeem(raw)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired stream ticket", "code": "INVALID_TICKET"})
+			return
+		}
+		if projectID, err := ProjectFromQuery(c); err != nil || projectID != ticket.ProjectID {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Ticket was issued for another project", "code": "PERMISSION_DENIED"})
+			return
+		}
+		user, err := tokens.ActiveUser(ticket.UserID)
+		if err != nil {
+			abortWithServiceError(c, err)
 			return
 		}
 
-		claims, err := auth.ValidateToken(token)
+		c.Set("user_id", ticket.UserID)
+		c.Set("org_id", ticket.OrganizationID)
+		c.Set("role", string(user.Role))
+		serveTracked(c, sessions, ticket.UserID, ticket.SessionID)
+	}
+}
+
+func authenticate(c *gin.Context, sessions realtime.SessionTracker, apiKeys *services.APIKeys, tokens *services.Tokens) {
+	if raw := c.GetHeader("X-API-Key"); raw != "" {
+		key, err := apiKeys.Authenticate(raw, c.ClientIP())
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+			abortWithServiceError(c, err)
 			return
 		}
+		c.Set("user_id", key.UserID)
+		c.Set("org_id", key.User.OrganizationID)
+		c.Set("role", string(key.User.Role))
+		c.Set("api_key", key)
+		serveTracked(c, sessions, key.UserID, apiKeys.SessionID(key))
+		return
+	}
+
+	token := ""
+	parts := strings.Split(c.GetHeader("Authorization"), " ")
+	if len(parts) == 2 && parts[0] == "Bearer" {
+		token = parts[1]
+	}
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization token is required", "code": "AUTH_REQUIRED"})
+		return
+	}
 
-		c.Set("user_id", claims.UserID)
-		c.Set("role", claims.Role)
+	claims, err := auth.Authenticate(token)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token", "code": "INVALID_TOKEN"})
+		return
+	}
+	user, err := tokens.ActiveUser(claims.UserID)
+	if err != nil {
+		abortWithServiceError(c, err)
+		return
+	}
+
+	c.Set("user_id", claims.UserID)
+	c.Set("org_id", claims.OrganizationID)
+	c.Set("role", string(user.Role))
+	c.Set("claims", claims)
+	serveTracked(c, sessions, claims.UserID, claims.ID)
+}
+
+// serveTracked runs the rest of the chain with a context that revoking the session cancels
+func serveTracked(c *gin.Context, sessions realtime.SessionTracker, userID uint, sessionID string) {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	untrack := sessions.TrackSession(userID, sessionID, cancel)
+	defer func() {
+		untrack()
+		cancel()
+	}()
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+}
+
+// RequireScope limits API keys on routes that have no RBAC middleware to check their scopes
+func RequireScope(obj string, act string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if scopeDenied(c, obj, act) {
+			return
+		}
 		c.Next()
 	}
 }
 
+// scopeDenied aborts requests made with an API key whose scopes do not cover obj and act
+func scopeDenied(c *gin.Context, obj string, act string) bool {
+	key, ok := c.Get("api_key")
+	if !ok || services.ScopesAllow(key.(*models.APIKey).Scopes, obj, act) {
+		return false
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key scope does not allow this action", "code": "SCOPE_NOT_GRANTED"})
+	return true
+}
+
 func RBACMiddleware(obj string, act string) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if scopeDenied(c, obj, act) {
+			return
+		}
+
 		role, exists := c.Get("role")
 		if !exists {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Role not found in context"})
-			c.Abort()
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Role not found in context", "code": "PERMISSION_DENIED"})
 			return
 		}
 
-		ok, err := auth.Enforcer.Enforce(role.(string), obj, act)
+		ok, err := auth.Enforce(role.(string), c.GetUint("org_id"), obj, act)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error during authorization check"})
-			c.Abort()
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error during authorization check", "code": "INTERNAL_ERROR"})
 			return
 		}
 
 		if !ok {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
-			c.Abort()
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission denied", "code": "PERMISSION_DENIED"})
 			return
 		}
 
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"backend/controllers"
+	"backend/services"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// ProjectScope extracts the project a request acts on
+type ProjectScope func(c *gin.Context) (uint, error)
+
+var errInvalidProjectScope = &services.Error{Kind: services.KindInvalid, Code: "INVALID_ID", Message: "A valid project is required"}
+
+// ProjectRBACMiddleware checks the Casbin policy against the caller's role in the
+// request's project instead of their global role. The resolved role and project
+// are stored as "project_role" and "project_id".
+func ProjectRBACMiddleware(access *services.Access, obj string, act string, scope ProjectScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if scopeDenied(c, obj, act) {
+			return
+		}
+
+		projectID, err := scope(c)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		role, err := access.Authorize(projectID, c.GetUint("user_id"), c.GetString("role"), obj, act)
+		if err != nil {
+			abortWithServiceError(c, err)
+			return
+		}
+
+		c.Set("project_id", projectID)
+		c.Set("project_role", string(role))
+		c.Next()
+	}
+}
+
+// ProjectFromQuery reads ?project_id=
+func ProjectFromQuery(c *gin.Context) (uint, error) {
+	return parseProjectID(c.Query("project_id"))
+}
+
+// ProjectFromParam reads the :id path parameter of /projects/:id routes
+func ProjectFromParam(c *gin.Context) (uint, error) {
+	return parseProjectID(c.Param("id"))
+}
+
+// ProjectFromBody reads "project_id" from a JSON body. The body is cached, so
+// handlers must bind it again with ShouldBindBodyWith.
+func ProjectFromBody(c *gin.Context) (uint, error) {
+	var body struct {
+		ProjectID uint `json:"project_id"`
+	}
+	if err := c.ShouldBindBodyWith(&body, binding.JSON); err != nil || body.ProjectID == 0 {
+		return 0, errInvalidProjectScope
+	}
+	return body.ProjectID, nil
+}
+
+// ProjectFromDrawing resolves the project of the drawing in the :id path parameter
+func ProjectFromDrawing(access *services.Access) ProjectScope {
+	return func(c *gin.Context) (uint, error) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return 0, &services.Error{Kind: services.KindInvalid, Code: "INVALID_ID", Message: "Invalid drawing id"}
+		}
+		return access.DrawingProject(uint(id))
+	}
+}
+
+func parseProjectID(raw string) (uint, error) {
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil || id == 0 {
+		return 0, errInvalidProjectScope
+	}
+	return uint(id), nil
+}
+
+func abortWithServiceError(c *gin.Context, err error) {
+	var domainErr *services.Error
+	if !errors.As(err, &domainErr) {
+		domainErr = services.Internal(err)
+	}
+	if domainErr.Kind == services.KindInternal {
+		log.Printf("internal error on %s %s: %v", c.Request.Method, c.FullPath(), domainErr.Err)
+	}
+	c.AbortWithStatusJSON(controllers.StatusFor(domainErr.Kind), gin.H{"error": domainErr.Message, "code": domainErr.Code})
+}
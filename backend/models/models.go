@@ -15,6 +15,22 @@ const (
 	RoleFinalQC   UserRole = "final_qc"
 )
 
+type UserStatus string
+
+const (
+	UserStatusActive      UserStatus = "active"
+	UserStatusPending     UserStatus = "pending"     // Self-registered, waiting for an admin to approve
+	UserStatusDeactivated UserStatus = "deactivated" // Left the organization; cannot log in
+)
+
+// MemberSource records how a project membership was granted
+type MemberSource string
+
+const (
+	MemberSourceManual MemberSource = "manual" // Added by an owner or admin, or through an invitation
+	MemberSourceGroup  MemberSource = "group"  // Granted by the SSO or LDAP group mapping; follows the user's groups
+)
+
 type Stage string
 
 const (
@@ -25,13 +41,33 @@ const (
 	StageApproved   Stage = "approved"
 )
 
+// DefaultOrganizationSlug names the organization that existing users and projects are migrated into
+const DefaultOrganizationSlug = "default"
+
+// Organization is a tenant. It owns users and projects, and nothing is shared across organizations.
+type Organization struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"uniqueIndex;not null" json:"name"`
+	Slug      string    `gorm:"uniqueIndex;not null" json:"slug"` // Given at login to pick the organization
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 type Project struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"uniqueIndex;not null" json:"name" binding:"required"`
-	Description string         `json:"description"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizationID uint           `gorm:"uniqueIndex:idx_projects_org_name_active,priority:1,where:deleted_at IS NULL;not null" json:"organization_id"`
+	Name           string         `gorm:"uniqueIndex:idx_projects_org_name_active,priority:2,where:deleted_at IS NULL;not null" json:"name" binding:"required"` // Unique among the organization's live projects only
+	Description    string         `json:"description"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Archived projects are read-only and hidden from default listings
+	ArchivedAt    *time.Time `gorm:"index" json:"archived_at"`
+	ArchiveReason string     `json:"archive_reason,omitempty"`
+
+	// Templates are starting points for POST /projects/:id/clone
+	IsTemplate bool `gorm:"not null;default:false;index" json:"is_template"`
 
 	// Relationships
 	Members  []User    `gorm:"many2many:project_members;" json:"members,omitempty"`
@@ -39,35 +75,48 @@ type Project struct {
 }
 
 type ProjectMember struct {
-	ProjectID uint      `gorm:"primaryKey"`
-	UserID    uint      `gorm:"primaryKey"`
-	Role      UserRole  `gorm:"not null"` // Role specifically within this project
-	JoinedAt  time.Time `gorm:"autoCreateTime"`
+	ProjectID uint         `gorm:"primaryKey" json:"project_id"`
+	UserID    uint         `gorm:"primaryKey" json:"user_id"`
+	User      *User        `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Role      UserRole     `gorm:"not null" json:"role"`                   // Role specifically within this project
+	IsOwner   bool         `gorm:"not null;default:false" json:"is_owner"` // Owners manage the project's membership
+	Source    MemberSource `gorm:"not null;default:manual" json:"source"`
+	JoinedAt  time.Time    `gorm:"autoCreateTime" json:"joined_at"`
 }
 
 type User struct {
-	ID           uint           `gorm:"primaryKey" json:"id"`
-	Username     string         `gorm:"uniqueIndex;not null" json:"username" binding:"required"`
-	PasswordHash string         `json:"-"`
-	Role         UserRole       `gorm:"not null" json:"role"` // System-wide default role
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	OrganizationID uint       `gorm:"uniqueIndex:idx_users_org_username,priority:1;not null" json:"organization_id"`
+	Username       string     `gorm:"uniqueIndex:idx_users_org_username,priority:2;not null" json:"username" binding:"required"` // Unique within the organization
+	PasswordHash   string     `json:"-"`
+	Email          string     `json:"email,omitempty"`      // Used for password reset mails
+	Role           UserRole   `gorm:"not null" json:"role"` // Organization-wide default role
+	Status         UserStatus `gorm:"not null;default:'active';index" json:"status"`
+	ServiceAccount bool       `gorm:"not null;default:false" json:"service_account"` // Authenticates with API keys only
+
+	// Set for users created by single sign-on; they have no password
+	ExternalIssuer  string  `gorm:"uniqueIndex:idx_users_external,priority:1;not null;default:''" json:"-"`
+	ExternalSubject *string `gorm:"uniqueIndex:idx_users_external,priority:2" json:"-"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 type Drawing struct {
 	ID          uint    `gorm:"primaryKey" json:"id"`
 	Title       string  `gorm:"uniqueIndex:idx_project_title;not null" json:"title" binding:"required"`
 	Description string  `json:"description"`
-	ProjectID   uint    `gorm:"uniqueIndex:idx_project_title;not null" json:"project_id"`
+	ProjectID   uint    `gorm:"uniqueIndex:idx_project_title;index:idx_drawings_project_stage,priority:1;not null" json:"project_id"`
 	Project     Project `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
 
 	AuthorID uint `gorm:"index" json:"author_id"` // Creator of the drawing (Nullable for existing)
 	Author   User `gorm:"foreignKey:AuthorID" json:"author,omitempty"`
 
-	CurrentStage Stage `gorm:"index;not null;default:'unassigned'" json:"current_stage"`
-	AssigneeID   *uint `gorm:"index" json:"assignee_id"`
-	Assignee     *User `gorm:"foreignKey:AssigneeID" json:"assignee,omitempty"`
+	CurrentStage   Stage     `gorm:"index;index:idx_drawings_project_stage,priority:2;not null;default:'unassigned'" json:"current_stage"`
+	StageChangedAt time.Time `gorm:"index:idx_drawings_project_stage,priority:3;not null;default:CURRENT_TIMESTAMP" json:"stage_changed_at"`
+	AssigneeID     *uint     `gorm:"index" json:"assignee_id"`
+	Assignee       *User     `gorm:"foreignKey:AssigneeID" json:"assignee,omitempty"`
 
 	Revision   int    `gorm:"not null;default:1" json:"revision"` // Business Revision (increases on rework/submit)
 	Version    int64  `gorm:"not null;default:0" json:"version"`  // Technical Concurrency Lock
@@ -89,3 +138,113 @@ type WorkflowLog struct {
 	Comment   string    `json:"comment"`
 	Timestamp time.Time `gorm:"autoCreateTime" json:"timestamp"`
 }
+
+// Invitation lets one person join an organization with a role and project
+// memberships chosen by the admin who issued it. Only the token's hash is stored.
+type Invitation struct {
+	ID             uint                   `gorm:"primaryKey" json:"id"`
+	OrganizationID uint                   `gorm:"not null;index" json:"organization_id"`
+	TokenHash      string                 `gorm:"uniqueIndex;not null" json:"-"`
+	Role           UserRole               `gorm:"not null" json:"role"`
+	Note           string                 `json:"note"` // Who the invitation is for, for the admins' benefit
+	Memberships    []InvitationMembership `gorm:"constraint:OnDelete:CASCADE" json:"memberships"`
+	CreatedByID    uint                   `gorm:"not null" json:"created_by_id"`
+	ExpiresAt      time.Time              `gorm:"not null" json:"expires_at"`
+	UsedAt         *time.Time             `json:"used_at"`
+	UsedByID       *uint                  `json:"used_by_id"`
+	RevokedAt      *time.Time             `json:"revoked_at"`
+	CreatedAt      time.Time              `json:"created_at"`
+}
+
+// InvitationMembership is a project membership granted when the invitation is accepted
+type InvitationMembership struct {
+	InvitationID uint     `gorm:"primaryKey" json:"-"`
+	ProjectID    uint     `gorm:"primaryKey" json:"project_id"`
+	Role         UserRole `gorm:"not null" json:"role"`
+	IsOwner      bool     `gorm:"not null;default:false" json:"is_owner"`
+}
+
+// RefreshToken is a single-use token for renewing access tokens. Only its SHA-256
+// hash is stored. Every rotation stays in the family of the login that started it,
+// so reusing a spent token can revoke the whole family.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID  string     `gorm:"not null;index" json:"family_id"`
+	TokenHash string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// PasswordHistory keeps a user's previous password hashes, so they cannot be reused
+type PasswordHistory struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"not null;index" json:"user_id"`
+	PasswordHash string    `gorm:"not null" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PasswordResetToken lets a user set a new password once, without knowing the old one.
+// Only the token's hash is stored. CreatedByID is set when an admin started the reset.
+type PasswordResetToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash   string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"`
+	CreatedByID *uint      `json:"created_by_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TOTPCredential is a user's authenticator app secret, encrypted with MFA_ENCRYPTION_KEY.
+// It only protects logins once confirmed. LastUsedStep is the time step of the last accepted
+// code, so a code cannot be used twice.
+type TOTPCredential struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"uniqueIndex;not null" json:"user_id"`
+	Secret       string     `gorm:"not null" json:"-"`
+	LastUsedStep int64      `gorm:"not null;default:0" json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// RecoveryCode is a single-use code for logging in without the authenticator app.
+// Only the code's hash is stored.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"uniqueIndex;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// APIKey lets a service account authenticate with the X-API-Key header. Only the key's
+// SHA-256 hash is stored; Prefix is the start of the key, so admins can tell keys apart.
+// Scopes are Casbin "object:action" pairs and may use "*" for either part.
+type APIKey struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	User        *User      `gorm:"foreignKey:UserID" json:"-"`
+	Name        string     `gorm:"not null" json:"name"`
+	Prefix      string     `gorm:"not null" json:"prefix"`
+	KeyHash     string     `gorm:"uniqueIndex;not null" json:"-"`
+	Scopes      []string   `gorm:"serializer:json;not null" json:"scopes"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `json:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedByID uint       `gorm:"not null" json:"created_by_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// AuditEvent records an administrative action that is not a workflow transition
+type AuditEvent struct {
+	Type         string                 `json:"type"` // e.g. "project.member_added"
+	ActorID      uint                   `json:"actor_id"`
+	ProjectID    uint                   `json:"project_id,omitempty"`
+	TargetUserID uint                   `json:"target_user_id,omitempty"`
+	Details      map[string]interface{} `json:"details,omitempty"`
+	Timestamp    time.Time              `json:"timestamp"`
+}
@@ -0,0 +1,751 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: qc/v1/qc.proto
+
+package qcv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Drawing struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	ProjectId     uint32                 `protobuf:"varint,4,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	AuthorId      uint32                 `protobuf:"varint,5,opt,name=author_id,json=authorId,proto3" json:"author_id,omitempty"`
+	CurrentStage  string                 `protobuf:"bytes,6,opt,name=current_stage,json=currentStage,proto3" json:"current_stage,omitempty"`
+	AssigneeId    *uint32                `protobuf:"varint,7,opt,name=assignee_id,json=assigneeId,proto3,oneof" json:"assignee_id,omitempty"`
+	Revision      int32                  `protobuf:"varint,8,opt,name=revision,proto3" json:"revision,omitempty"`
+	Version       int64                  `protobuf:"varint,9,opt,name=version,proto3" json:"version,omitempty"`
+	DrawingUrl    string                 `protobuf:"bytes,10,opt,name=drawing_url,json=drawingUrl,proto3" json:"drawing_url,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Drawing) Reset() {
+	*x = Drawing{}
+	mi := &file_qc_v1_qc_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Drawing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Drawing) ProtoMessage() {}
+
+func (x *Drawing) ProtoReflect() protoreflect.Message {
+	mi := &file_qc_v1_qc_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Drawing.ProtoReflect.Descriptor instead.
+func (*Drawing) Descriptor() ([]byte, []int) {
+	return file_qc_v1_qc_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Drawing) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Drawing) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Drawing) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Drawing) GetProjectId() uint32 {
+	if x != nil {
+		return x.ProjectId
+	}
+	return 0
+}
+
+func (x *Drawing) GetAuthorId() uint32 {
+	if x != nil {
+		return x.AuthorId
+	}
+	return 0
+}
+
+func (x *Drawing) GetCurrentStage() string {
+	if x != nil {
+		return x.CurrentStage
+	}
+	return ""
+}
+
+func (x *Drawing) GetAssigneeId() uint32 {
+	if x != nil && x.AssigneeId != nil {
+		return *x.AssigneeId
+	}
+	return 0
+}
+
+func (x *Drawing) GetRevision() int32 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *Drawing) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Drawing) GetDrawingUrl() string {
+	if x != nil {
+		return x.DrawingUrl
+	}
+	return ""
+}
+
+func (x *Drawing) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Drawing) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ListDrawingsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Zero lists drawings across every project the caller belongs to.
+	ProjectId     uint32 `protobuf:"varint,1,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDrawingsRequest) Reset() {
+	*x = ListDrawingsRequest{}
+	mi := &file_qc_v1_qc_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDrawingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDrawingsRequest) ProtoMessage() {}
+
+func (x *ListDrawingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qc_v1_qc_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDrawingsRequest.ProtoReflect.Descriptor instead.
+func (*ListDrawingsRequest) Descriptor() ([]byte, []int) {
+	return file_qc_v1_qc_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListDrawingsRequest) GetProjectId() uint32 {
+	if x != nil {
+		return x.ProjectId
+	}
+	return 0
+}
+
+type ListDrawingsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Drawings      []*Drawing             `protobuf:"bytes,1,rep,name=drawings,proto3" json:"drawings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDrawingsResponse) Reset() {
+	*x = ListDrawingsResponse{}
+	mi := &file_qc_v1_qc_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDrawingsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDrawingsResponse) ProtoMessage() {}
+
+func (x *ListDrawingsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_qc_v1_qc_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDrawingsResponse.ProtoReflect.Descriptor instead.
+func (*ListDrawingsResponse) Descriptor() ([]byte, []int) {
+	return file_qc_v1_qc_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListDrawingsResponse) GetDrawings() []*Drawing {
+	if x != nil {
+		return x.Drawings
+	}
+	return nil
+}
+
+type GetDrawingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDrawingRequest) Reset() {
+	*x = GetDrawingRequest{}
+	mi := &file_qc_v1_qc_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDrawingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDrawingRequest) ProtoMessage() {}
+
+func (x *GetDrawingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qc_v1_qc_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDrawingRequest.ProtoReflect.Descriptor instead.
+func (*GetDrawingRequest) Descriptor() ([]byte, []int) {
+	return file_qc_v1_qc_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetDrawingRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type CreateDrawingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	ProjectId     uint32                 `protobuf:"varint,3,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateDrawingRequest) Reset() {
+	*x = CreateDrawingRequest{}
+	mi := &file_qc_v1_qc_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateDrawingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateDrawingRequest) ProtoMessage() {}
+
+func (x *CreateDrawingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qc_v1_qc_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDrawingRequest.ProtoReflect.Descriptor instead.
+func (*CreateDrawingRequest) Descriptor() ([]byte, []int) {
+	return file_qc_v1_qc_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreateDrawingRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *CreateDrawingRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *CreateDrawingRequest) GetProjectId() uint32 {
+	if x != nil {
+		return x.ProjectId
+	}
+	return 0
+}
+
+type UpdateDrawingRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title       string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Description string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	// Rejected with ABORTED if the drawing has changed since it was read.
+	ExpectedVersion int64 `protobuf:"varint,4,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *UpdateDrawingRequest) Reset() {
+	*x = UpdateDrawingRequest{}
+	mi := &file_qc_v1_qc_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateDrawingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateDrawingRequest) ProtoMessage() {}
+
+func (x *UpdateDrawingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qc_v1_qc_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateDrawingRequest.ProtoReflect.Descriptor instead.
+func (*UpdateDrawingRequest) Descriptor() ([]byte, []int) {
+	return file_qc_v1_qc_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateDrawingRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateDrawingRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *UpdateDrawingRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *UpdateDrawingRequest) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
+type DeleteDrawingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteDrawingRequest) Reset() {
+	*x = DeleteDrawingRequest{}
+	mi := &file_qc_v1_qc_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteDrawingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteDrawingRequest) ProtoMessage() {}
+
+func (x *DeleteDrawingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qc_v1_qc_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteDrawingRequest.ProtoReflect.Descriptor instead.
+func (*DeleteDrawingRequest) Descriptor() ([]byte, []int) {
+	return file_qc_v1_qc_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteDrawingRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type WorkflowActionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WorkflowActionRequest) Reset() {
+	*x = WorkflowActionRequest{}
+	mi := &file_qc_v1_qc_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WorkflowActionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WorkflowActionRequest) ProtoMessage() {}
+
+func (x *WorkflowActionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qc_v1_qc_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WorkflowActionRequest.ProtoReflect.Descriptor instead.
+func (*WorkflowActionRequest) Descriptor() ([]byte, []int) {
+	return file_qc_v1_qc_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *WorkflowActionRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type WatchProjectRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProjectId     uint32                 `protobuf:"varint,1,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchProjectRequest) Reset() {
+	*x = WatchProjectRequest{}
+	mi := &file_qc_v1_qc_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchProjectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchProjectRequest) ProtoMessage() {}
+
+func (x *WatchProjectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_qc_v1_qc_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchProjectRequest.ProtoReflect.Descriptor instead.
+func (*WatchProjectRequest) Descriptor() ([]byte, []int) {
+	return file_qc_v1_qc_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WatchProjectRequest) GetProjectId() uint32 {
+	if x != nil {
+		return x.ProjectId
+	}
+	return 0
+}
+
+type ProjectEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// JSON-encoded payload, identical to the SSE stream.
+	PayloadJson   string `protobuf:"bytes,2,opt,name=payload_json,json=payloadJson,proto3" json:"payload_json,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProjectEvent) Reset() {
+	*x = ProjectEvent{}
+	mi := &file_qc_v1_qc_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProjectEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProjectEvent) ProtoMessage() {}
+
+func (x *ProjectEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_qc_v1_qc_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProjectEvent.ProtoReflect.Descriptor instead.
+func (*ProjectEvent) Descriptor() ([]byte, []int) {
+	return file_qc_v1_qc_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ProjectEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ProjectEvent) GetPayloadJson() string {
+	if x != nil {
+		return x.PayloadJson
+	}
+	return ""
+}
+
+var File_qc_v1_qc_proto protoreflect.FileDescriptor
+
+const file_qc_v1_qc_proto_rawDesc = "" +
+	"\n" +
+	"\x0eqc/v1/qc.proto\x12\x05qc.v1\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb5\x03\n" +
+	"\aDrawing\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x04 \x01(\rR\tprojectId\x12\x1b\n" +
+	"\tauthor_id\x18\x05 \x01(\rR\bauthorId\x12#\n" +
+	"\rcurrent_stage\x18\x06 \x01(\tR\fcurrentStage\x12$\n" +
+	"\vassignee_id\x18\a \x01(\rH\x00R\n" +
+	"assigneeId\x88\x01\x01\x12\x1a\n" +
+	"\brevision\x18\b \x01(\x05R\brevision\x12\x18\n" +
+	"\aversion\x18\t \x01(\x03R\aversion\x12\x1f\n" +
+	"\vdrawing_url\x18\n" +
+	" \x01(\tR\n" +
+	"drawingUrl\x129\n" +
+	"\n" +
+	"created_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\x0e\n" +
+	"\f_assignee_id\"4\n" +
+	"\x13ListDrawingsRequest\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x01 \x01(\rR\tprojectId\"B\n" +
+	"\x14ListDrawingsResponse\x12*\n" +
+	"\bdrawings\x18\x01 \x03(\v2\x0e.qc.v1.DrawingR\bdrawings\"#\n" +
+	"\x11GetDrawingRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\"m\n" +
+	"\x14CreateDrawingRequest\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x03 \x01(\rR\tprojectId\"\x89\x01\n" +
+	"\x14UpdateDrawingRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12)\n" +
+	"\x10expected_version\x18\x04 \x01(\x03R\x0fexpectedVersion\"&\n" +
+	"\x14DeleteDrawingRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\"'\n" +
+	"\x15WorkflowActionRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\"4\n" +
+	"\x13WatchProjectRequest\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x01 \x01(\rR\tprojectId\"E\n" +
+	"\fProjectEvent\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12!\n" +
+	"\fpayload_json\x18\x02 \x01(\tR\vpayloadJson2\x92\x05\n" +
+	"\x0eDrawingService\x12G\n" +
+	"\fListDrawings\x12\x1a.qc.v1.ListDrawingsRequest\x1a\x1b.qc.v1.ListDrawingsResponse\x126\n" +
+	"\n" +
+	"GetDrawing\x12\x18.qc.v1.GetDrawingRequest\x1a\x0e.qc.v1.Drawing\x12<\n" +
+	"\rCreateDrawing\x12\x1b.qc.v1.CreateDrawingRequest\x1a\x0e.qc.v1.Drawing\x12<\n" +
+	"\rUpdateDrawing\x12\x1b.qc.v1.UpdateDrawingRequest\x1a\x0e.qc.v1.Drawing\x12D\n" +
+	"\rDeleteDrawing\x12\x1b.qc.v1.DeleteDrawingRequest\x1a\x16.google.protobuf.Empty\x12<\n" +
+	"\fClaimDrawing\x12\x1c.qc.v1.WorkflowActionRequest\x1a\x0e.qc.v1.Drawing\x12=\n" +
+	"\rSubmitDrawing\x12\x1c.qc.v1.WorkflowActionRequest\x1a\x0e.qc.v1.Drawing\x12>\n" +
+	"\x0eReleaseDrawing\x12\x1c.qc.v1.WorkflowActionRequest\x1a\x0e.qc.v1.Drawing\x12=\n" +
+	"\rRejectDrawing\x12\x1c.qc.v1.WorkflowActionRequest\x1a\x0e.qc.v1.Drawing\x12A\n" +
+	"\fWatchProject\x12\x1a.qc.v1.WatchProjectRequest\x1a\x13.qc.v1.ProjectEvent0\x01B\x1aZ\x18backend/proto/qc/v1;qcv1b\x06proto3"
+
+var (
+	file_qc_v1_qc_proto_rawDescOnce sync.Once
+	file_qc_v1_qc_proto_rawDescData []byte
+)
+
+func file_qc_v1_qc_proto_rawDescGZIP() []byte {
+	file_qc_v1_qc_proto_rawDescOnce.Do(func() {
+		file_qc_v1_qc_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_qc_v1_qc_proto_rawDesc), len(file_qc_v1_qc_proto_rawDesc)))
+	})
+	return file_qc_v1_qc_proto_rawDescData
+}
+
+var file_qc_v1_qc_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_qc_v1_qc_proto_goTypes = []any{
+	(*Drawing)(nil),               // 0: qc.v1.Drawing
+	(*ListDrawingsRequest)(nil),   // 1: qc.v1.ListDrawingsRequest
+	(*ListDrawingsResponse)(nil),  // 2: qc.v1.ListDrawingsResponse
+	(*GetDrawingRequest)(nil),     // 3: qc.v1.GetDrawingRequest
+	(*CreateDrawingRequest)(nil),  // 4: qc.v1.CreateDrawingRequest
+	(*UpdateDrawingRequest)(nil),  // 5: qc.v1.UpdateDrawingRequest
+	(*DeleteDrawingRequest)(nil),  // 6: qc.v1.DeleteDrawingRequest
+	(*WorkflowActionRequest)(nil), // 7: qc.v1.WorkflowActionRequest
+	(*WatchProjectRequest)(nil),   // 8: qc.v1.WatchProjectRequest
+	(*ProjectEvent)(nil),          // 9: qc.v1.ProjectEvent
+	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),         // 11: google.protobuf.Empty
+}
+var file_qc_v1_qc_proto_depIdxs = []int32{
+	10, // 0: qc.v1.Drawing.created_at:type_name -> google.protobuf.Timestamp
+	10, // 1: qc.v1.Drawing.updated_at:type_name -> google.protobuf.Timestamp
+	0,  // 2: qc.v1.ListDrawingsResponse.drawings:type_name -> qc.v1.Drawing
+	1,  // 3: qc.v1.DrawingService.ListDrawings:input_type -> qc.v1.ListDrawingsRequest
+	3,  // 4: qc.v1.DrawingService.GetDrawing:input_type -> qc.v1.GetDrawingRequest
+	4,  // 5: qc.v1.DrawingService.CreateDrawing:input_type -> qc.v1.CreateDrawingRequest
+	5,  // 6: qc.v1.DrawingService.UpdateDrawing:input_type -> qc.v1.UpdateDrawingRequest
+	6,  // 7: qc.v1.DrawingService.DeleteDrawing:input_type -> qc.v1.DeleteDrawingRequest
+	7,  // 8: qc.v1.DrawingService.ClaimDrawing:input_type -> qc.v1.WorkflowActionRequest
+	7,  // 9: qc.v1.DrawingService.SubmitDrawing:input_type -> qc.v1.WorkflowActionRequest
+	7,  // 10: qc.v1.DrawingService.ReleaseDrawing:input_type -> qc.v1.WorkflowActionRequest
+	7,  // 11: qc.v1.DrawingService.RejectDrawing:input_type -> qc.v1.WorkflowActionRequest
+	8,  // 12: qc.v1.DrawingService.WatchProject:input_type -> qc.v1.WatchProjectRequest
+	2,  // 13: qc.v1.DrawingService.ListDrawings:output_type -> qc.v1.ListDrawingsResponse
+	0,  // 14: qc.v1.DrawingService.GetDrawing:output_type -> qc.v1.Drawing
+	0,  // 15: qc.v1.DrawingService.CreateDrawing:output_type -> qc.v1.Drawing
+	0,  // 16: qc.v1.DrawingService.UpdateDrawing:output_type -> qc.v1.Drawing
+	11, // 17: qc.v1.DrawingService.DeleteDrawing:output_type -> google.protobuf.Empty
+	0,  // 18: qc.v1.DrawingService.ClaimDrawing:output_type -> qc.v1.Drawing
+	0,  // 19: qc.v1.DrawingService.SubmitDrawing:output_type -> qc.v1.Drawing
+	0,  // 20: qc.v1.DrawingService.ReleaseDrawing:output_type -> qc.v1.Drawing
+	0,  // 21: qc.v1.DrawingService.RejectDrawing:output_type -> qc.v1.Drawing
+	9,  // 22: qc.v1.DrawingService.WatchProject:output_type -> qc.v1.ProjectEvent
+	13, // [13:23] is the sub-list for method output_type
+	3,  // [3:13] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_qc_v1_qc_proto_init() }
+func file_qc_v1_qc_proto_init() {
+	if File_qc_v1_qc_proto != nil {
+		return
+	}
+	file_qc_v1_qc_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_qc_v1_qc_proto_rawDesc), len(file_qc_v1_qc_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_qc_v1_qc_proto_goTypes,
+		DependencyIndexes: file_qc_v1_qc_proto_depIdxs,
+		MessageInfos:      file_qc_v1_qc_proto_msgTypes,
+	}.Build()
+	File_qc_v1_qc_proto = out.File
+	file_qc_v1_qc_proto_goTypes = nil
+	file_qc_v1_qc_proto_depIdxs = nil
+}
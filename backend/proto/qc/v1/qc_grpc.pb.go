@@ -0,0 +1,476 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: qc/v1/qc.proto
+
+package qcv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	DrawingService_ListDrawings_FullMethodName   = "/qc.v1.DrawingService/ListDrawings"
+	DrawingService_GetDrawing_FullMethodName     = "/qc.v1.DrawingService/GetDrawing"
+	DrawingService_CreateDrawing_FullMethodName  = "/qc.v1.DrawingService/CreateDrawing"
+	DrawingService_UpdateDrawing_FullMethodName  = "/qc.v1.DrawingService/UpdateDrawing"
+	DrawingService_DeleteDrawing_FullMethodName  = "/qc.v1.DrawingService/DeleteDrawing"
+	DrawingService_ClaimDrawing_FullMethodName   = "/qc.v1.DrawingService/ClaimDrawing"
+	DrawingService_SubmitDrawing_FullMethodName  = "/qc.v1.DrawingService/SubmitDrawing"
+	DrawingService_ReleaseDrawing_FullMethodName = "/qc.v1.DrawingService/ReleaseDrawing"
+	DrawingService_RejectDrawing_FullMethodName  = "/qc.v1.DrawingService/RejectDrawing"
+	DrawingService_WatchProject_FullMethodName   = "/qc.v1.DrawingService/WatchProject"
+)
+
+// DrawingServiceClient is the client API for DrawingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// DrawingService exposes drawing management and the review workflow to CAD plugins.
+// Every call must carry "authorization: Bearer <jwt>" metadata.
+type DrawingServiceClient interface {
+	ListDrawings(ctx context.Context, in *ListDrawingsRequest, opts ...grpc.CallOption) (*ListDrawingsResponse, error)
+	GetDrawing(ctx context.Context, in *GetDrawingRequest, opts ...grpc.CallOption) (*Drawing, error)
+	CreateDrawing(ctx context.Context, in *CreateDrawingRequest, opts ...grpc.CallOption) (*Drawing, error)
+	UpdateDrawing(ctx context.Context, in *UpdateDrawingRequest, opts ...grpc.CallOption) (*Drawing, error)
+	DeleteDrawing(ctx context.Context, in *DeleteDrawingRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	ClaimDrawing(ctx context.Context, in *WorkflowActionRequest, opts ...grpc.CallOption) (*Drawing, error)
+	SubmitDrawing(ctx context.Context, in *WorkflowActionRequest, opts ...grpc.CallOption) (*Drawing, error)
+	ReleaseDrawing(ctx context.Context, in *WorkflowActionRequest, opts ...grpc.CallOption) (*Drawing, error)
+	RejectDrawing(ctx context.Context, in *WorkflowActionRequest, opts ...grpc.CallOption) (*Drawing, error)
+	// WatchProject streams the same events as the SSE endpoint until the client disconnects.
+	WatchProject(ctx context.Context, in *WatchProjectRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProjectEvent], error)
+}
+
+type drawingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDrawingServiceClient(cc grpc.ClientConnInterface) DrawingServiceClient {
+	return &drawingServiceClient{cc}
+}
+
+func (c *drawingServiceClient) ListDrawings(ctx context.Context, in *ListDrawingsRequest, opts ...grpc.CallOption) (*ListDrawingsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDrawingsResponse)
+	err := c.cc.Invoke(ctx, DrawingService_ListDrawings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *drawingServiceClient) GetDrawing(ctx context.Context, in *GetDrawingRequest, opts ...grpc.CallOption) (*Drawing, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Drawing)
+	err := c.cc.Invoke(ctx, DrawingService_GetDrawing_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *drawingServiceClient) CreateDrawing(ctx context.Context, in *CreateDrawingRequest, opts ...grpc.CallOption) (*Drawing, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Drawing)
+	err := c.cc.Invoke(ctx, DrawingService_CreateDrawing_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *drawingServiceClient) UpdateDrawing(ctx context.Context, in *UpdateDrawingRequest, opts ...grpc.CallOption) (*Drawing, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Drawing)
+	err := c.cc.Invoke(ctx, DrawingService_UpdateDrawing_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *drawingServiceClient) DeleteDrawing(ctx context.Context, in *DeleteDrawingRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, DrawingService_DeleteDrawing_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *drawingServiceClient) ClaimDrawing(ctx context.Context, in *WorkflowActionRequest, opts ...grpc.CallOption) (*Drawing, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Drawing)
+	err := c.cc.Invoke(ctx, DrawingService_ClaimDrawing_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *drawingServiceClient) SubmitDrawing(ctx context.Context, in *WorkflowActionRequest, opts ...grpc.CallOption) (*Drawing, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Drawing)
+	err := c.cc.Invoke(ctx, DrawingService_SubmitDrawing_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *drawingServiceClient) ReleaseDrawing(ctx context.Context, in *WorkflowActionRequest, opts ...grpc.CallOption) (*Drawing, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Drawing)
+	err := c.cc.Invoke(ctx, DrawingService_ReleaseDrawing_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *drawingServiceClient) RejectDrawing(ctx context.Context, in *WorkflowActionRequest, opts ...grpc.CallOption) (*Drawing, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Drawing)
+	err := c.cc.Invoke(ctx, DrawingService_RejectDrawing_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *drawingServiceClient) WatchProject(ctx context.Context, in *WatchProjectRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProjectEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DrawingService_ServiceDesc.Streams[0], DrawingService_WatchProject_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchProjectRequest, ProjectEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DrawingService_WatchProjectClient = grpc.ServerStreamingClient[ProjectEvent]
+
+// DrawingServiceServer is the server API for DrawingService service.
+// All implementations must embed UnimplementedDrawingServiceServer
+// for forward compatibility.
+//
+// DrawingService exposes drawing management and the review workflow to CAD plugins.
+// Every call must carry "authorization: Bearer <jwt>" metadata.
+type DrawingServiceServer interface {
+	ListDrawings(context.Context, *ListDrawingsRequest) (*ListDrawingsResponse, error)
+	GetDrawing(context.Context, *GetDrawingRequest) (*Drawing, error)
+	CreateDrawing(context.Context, *CreateDrawingRequest) (*Drawing, error)
+	UpdateDrawing(context.Context, *UpdateDrawingRequest) (*Drawing, error)
+	DeleteDrawing(context.Context, *DeleteDrawingRequest) (*emptypb.Empty, error)
+	ClaimDrawing(context.Context, *WorkflowActionRequest) (*Drawing, error)
+	SubmitDrawing(context.Context, *WorkflowActionRequest) (*Drawing, error)
+	ReleaseDrawing(context.Context, *WorkflowActionRequest) (*Drawing, error)
+	RejectDrawing(context.Context, *WorkflowActionRequest) (*Drawing, error)
+	// WatchProject streams the same events as the SSE endpoint until the client disconnects.
+	WatchProject(*WatchProjectRequest, grpc.ServerStreamingServer[ProjectEvent]) error
+	mustEmbedUnimplementedDrawingServiceServer()
+}
+
+// UnimplementedDrawingServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDrawingServiceServer struct{}
+
+func (UnimplementedDrawingServiceServer) ListDrawings(context.Context, *ListDrawingsRequest) (*ListDrawingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDrawings not implemented")
+}
+func (UnimplementedDrawingServiceServer) GetDrawing(context.Context, *GetDrawingRequest) (*Drawing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDrawing not implemented")
+}
+func (UnimplementedDrawingServiceServer) CreateDrawing(context.Context, *CreateDrawingRequest) (*Drawing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateDrawing not implemented")
+}
+func (UnimplementedDrawingServiceServer) UpdateDrawing(context.Context, *UpdateDrawingRequest) (*Drawing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateDrawing not implemented")
+}
+func (UnimplementedDrawingServiceServer) DeleteDrawing(context.Context, *DeleteDrawingRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteDrawing not implemented")
+}
+func (UnimplementedDrawingServiceServer) ClaimDrawing(context.Context, *WorkflowActionRequest) (*Drawing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClaimDrawing not implemented")
+}
+func (UnimplementedDrawingServiceServer) SubmitDrawing(context.Context, *WorkflowActionRequest) (*Drawing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitDrawing not implemented")
+}
+func (UnimplementedDrawingServiceServer) ReleaseDrawing(context.Context, *WorkflowActionRequest) (*Drawing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseDrawing not implemented")
+}
+func (UnimplementedDrawingServiceServer) RejectDrawing(context.Context, *WorkflowActionRequest) (*Drawing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RejectDrawing not implemented")
+}
+func (UnimplementedDrawingServiceServer) WatchProject(*WatchProjectRequest, grpc.ServerStreamingServer[ProjectEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchProject not implemented")
+}
+func (UnimplementedDrawingServiceServer) mustEmbedUnimplementedDrawingServiceServer() {}
+func (UnimplementedDrawingServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeDrawingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DrawingServiceServer will
+// result in compilation errors.
+type UnsafeDrawingServiceServer interface {
+	mustEmbedUnimplementedDrawingServiceServer()
+}
+
+func RegisterDrawingServiceServer(s grpc.ServiceRegistrar, srv DrawingServiceServer) {
+	// If the following call pancis, it indicates UnimplementedDrawingServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&DrawingService_ServiceDesc, srv)
+}
+
+func _DrawingService_ListDrawings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDrawingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DrawingServiceServer).ListDrawings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DrawingService_ListDrawings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DrawingServiceServer).ListDrawings(ctx, req.(*ListDrawingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DrawingService_GetDrawing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDrawingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DrawingServiceServer).GetDrawing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DrawingService_GetDrawing_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DrawingServiceServer).GetDrawing(ctx, req.(*GetDrawingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DrawingService_CreateDrawing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateDrawingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DrawingServiceServer).CreateDrawing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DrawingService_CreateDrawing_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DrawingServiceServer).CreateDrawing(ctx, req.(*CreateDrawingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DrawingService_UpdateDrawing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateDrawingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DrawingServiceServer).UpdateDrawing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DrawingService_UpdateDrawing_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DrawingServiceServer).UpdateDrawing(ctx, req.(*UpdateDrawingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DrawingService_DeleteDrawing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteDrawingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DrawingServiceServer).DeleteDrawing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DrawingService_DeleteDrawing_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DrawingServiceServer).DeleteDrawing(ctx, req.(*DeleteDrawingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DrawingService_ClaimDrawing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkflowActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DrawingServiceServer).ClaimDrawing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DrawingService_ClaimDrawing_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DrawingServiceServer).ClaimDrawing(ctx, req.(*WorkflowActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DrawingService_SubmitDrawing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkflowActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DrawingServiceServer).SubmitDrawing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DrawingService_SubmitDrawing_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DrawingServiceServer).SubmitDrawing(ctx, req.(*WorkflowActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DrawingService_ReleaseDrawing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkflowActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DrawingServiceServer).ReleaseDrawing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DrawingService_ReleaseDrawing_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DrawingServiceServer).ReleaseDrawing(ctx, req.(*WorkflowActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DrawingService_RejectDrawing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkflowActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DrawingServiceServer).RejectDrawing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DrawingService_RejectDrawing_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DrawingServiceServer).RejectDrawing(ctx, req.(*WorkflowActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DrawingService_WatchProject_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchProjectRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DrawingServiceServer).WatchProject(m, &grpc.GenericServerStream[WatchProjectRequest, ProjectEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DrawingService_WatchProjectServer = grpc.ServerStreamingServer[ProjectEvent]
+
+// DrawingService_ServiceDesc is the grpc.ServiceDesc for DrawingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DrawingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "qc.v1.DrawingService",
+	HandlerType: (*DrawingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListDrawings",
+			Handler:    _DrawingService_ListDrawings_Handler,
+		},
+		{
+			MethodName: "GetDrawing",
+			Handler:    _DrawingService_GetDrawing_Handler,
+		},
+		{
+			MethodName: "CreateDrawing",
+			Handler:    _DrawingService_CreateDrawing_Handler,
+		},
+		{
+			MethodName: "UpdateDrawing",
+			Handler:    _DrawingService_UpdateDrawing_Handler,
+		},
+		{
+			MethodName: "DeleteDrawing",
+			Handler:    _DrawingService_DeleteDrawing_Handler,
+		},
+		{
+			MethodName: "ClaimDrawing",
+			Handler:    _DrawingService_ClaimDrawing_Handler,
+		},
+		{
+			MethodName: "SubmitDrawing",
+			Handler:    _DrawingService_SubmitDrawing_Handler,
+		},
+		{
+			MethodName: "ReleaseDrawing",
+			Handler:    _DrawingService_ReleaseDrawing_Handler,
+		},
+		{
+			MethodName: "RejectDrawing",
+			Handler:    _DrawingService_RejectDrawing_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchProject",
+			Handler:       _DrawingService_WatchProject_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "qc/v1/qc.proto",
+}
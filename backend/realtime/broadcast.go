@@ -7,8 +7,6 @@ import (
 	"log"
 	"sync"
 
-	"backend/config"
-
 	"github.com/redis/go-redis/v9"
 )
 
@@ -18,34 +16,33 @@ type Broadcaster interface {
 	Unsubscribe(projectID uint, ch chan string)
 }
 
-type Service struct {
-	redisClient *redis.Client
-	ctx         context.Context
-	subscribers map[uint]map[chan string]bool
-	subMu       sync.RWMutex
+// OrganizationResolver finds the organization a project belongs to
+type OrganizationResolver interface {
+	OrganizationOf(projectID uint) (uint, error)
 }
 
-func New(cfg *config.Config) *Service {
-	client := redis.NewClient(&redis.Options{
-		Addr: cfg.RedisURL,
-	})
-
-	ctx := context.Background()
-	_, err := client.Ping(ctx).Result()
-	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
-	}
-
-	log.Println("Redis connection established")
+type Service struct {
+	redisClient   *redis.Client
+	ctx           context.Context
+	subscribers   map[uint]map[chan string]bool
+	subMu         sync.RWMutex
+	organizations OrganizationResolver
+	projectOrgs   sync.Map // projectID -> organizationID; a project never changes organization
+	sessions      *sessionRegistry
+}
 
+func New(client *redis.Client, organizations OrganizationResolver) *Service {
 	s := &Service{
-		redisClient: client,
-		ctx:         ctx,
-		subscribers: make(map[uint]map[chan string]bool),
+		redisClient:   client,
+		ctx:           context.Background(),
+		subscribers:   make(map[uint]map[chan string]bool),
+		organizations: organizations,
+		sessions:      newSessionRegistry(),
 	}
 
 	// Start a global subscriber for this instance to listen to Redis Pub/Sub
 	go s.listenToRedis()
+	go s.listenForRevocations()
 
 	return s
 }
@@ -62,13 +59,31 @@ func (s *Service) BroadcastEvent(projectID uint, eventType string, payload inter
 	}
 	data, _ := json.Marshal(event)
 
-	channel := fmt.Sprintf("project:%d:events", projectID)
-	err := s.redisClient.Publish(s.ctx, channel, data).Err()
+	organizationID, err := s.organizationOf(projectID)
+	if err != nil {
+		log.Printf("Failed to resolve organization of project %d: %v", projectID, err)
+		return
+	}
+
+	channel := fmt.Sprintf("org:%d:project:%d:events", organizationID, projectID)
+	err = s.redisClient.Publish(s.ctx, channel, data).Err()
 	if err != nil {
 		log.Printf("Failed to publish to Redis: %v", err)
 	}
 }
 
+func (s *Service) organizationOf(projectID uint) (uint, error) {
+	if organizationID, ok := s.projectOrgs.Load(projectID); ok {
+		return organizationID.(uint), nil
+	}
+	organizationID, err := s.organizations.OrganizationOf(projectID)
+	if err != nil {
+		return 0, err
+	}
+	s.projectOrgs.Store(projectID, organizationID)
+	return organizationID, nil
+}
+
 func (s *Service) Subscribe(projectID uint) chan string {
 	s.subMu.Lock()
 	defer s.subMu.Unlock()
@@ -93,14 +108,19 @@ func (s *Service) Unsubscribe(projectID uint, ch chan string) {
 }
 
 func (s *Service) listenToRedis() {
-	// Pattern subscribe to all project channels
-	pubsub := s.redisClient.PSubscribe(s.ctx, "project:*:events")
+	// Pattern subscribe to all project channels of every organization
+	pubsub := s.redisClient.PSubscribe(s.ctx, "org:*:project:*:events")
 	defer pubsub.Close()
 
 	ch := pubsub.Channel()
 	for msg := range ch {
-		var projectID uint
-		fmt.Sscanf(msg.Channel, "project:%d:events", &projectID)
+		var organizationID, projectID uint
+		fmt.Sscanf(msg.Channel, "org:%d:project:%d:events", &organizationID, &projectID)
+
+		// Drop messages whose organization does not own the project
+		if owner, err := s.organizationOf(projectID); err != nil || owner != organizationID {
+			continue
+		}
 
 		s.subMu.RLock()
 		if subs, ok := s.subscribers[projectID]; ok {
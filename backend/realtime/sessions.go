@@ -0,0 +1,92 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+const sessionsChannel = "sessions:revoked"
+
+// SessionTracker lets long-lived requests (SSE, GraphQL subscriptions, gRPC streams)
+// be cut off when their token is revoked, on whichever instance serves them.
+type SessionTracker interface {
+	TrackSession(userID uint, jti string, cancel context.CancelFunc) func()
+	TerminateSessions(userID uint, jti string)
+}
+
+type revocationMessage struct {
+	UserID uint   `json:"user_id"`
+	JTI    string `json:"jti,omitempty"` // Empty means every session of the user
+}
+
+type trackedSession struct {
+	jti    string
+	cancel context.CancelFunc
+}
+
+type sessionRegistry struct {
+	mu       sync.Mutex
+	sessions map[uint]map[*trackedSession]bool
+}
+
+func newSessionRegistry() *sessionRegistry {
+	return &sessionRegistry{sessions: make(map[uint]map[*trackedSession]bool)}
+}
+
+// TrackSession registers the cancel func of a request made with the given token.
+// The returned func must be called when the request ends.
+func (s *Service) TrackSession(userID uint, jti string, cancel context.CancelFunc) func() {
+	session := &trackedSession{jti: jti, cancel: cancel}
+
+	s.sessions.mu.Lock()
+	if s.sessions.sessions[userID] == nil {
+		s.sessions.sessions[userID] = make(map[*trackedSession]bool)
+	}
+	s.sessions.sessions[userID][session] = true
+	s.sessions.mu.Unlock()
+
+	return func() {
+		s.sessions.mu.Lock()
+		defer s.sessions.mu.Unlock()
+		delete(s.sessions.sessions[userID], session)
+		if len(s.sessions.sessions[userID]) == 0 {
+			delete(s.sessions.sessions, userID)
+		}
+	}
+}
+
+// TerminateSessions cancels the user's open requests on every instance; an empty jti means all of them
+func (s *Service) TerminateSessions(userID uint, jti string) {
+	data, _ := json.Marshal(revocationMessage{UserID: userID, JTI: jti})
+	if err := s.redisClient.Publish(s.ctx, sessionsChannel, data).Err(); err != nil {
+		log.Printf("Failed to publish session revocation: %v", err)
+		// Still cut off the sessions this instance serves
+		s.cancelSessions(userID, jti)
+	}
+}
+
+func (s *Service) cancelSessions(userID uint, jti string) {
+	s.sessions.mu.Lock()
+	defer s.sessions.mu.Unlock()
+
+	for session := range s.sessions.sessions[userID] {
+		if jti == "" || session.jti == jti {
+			session.cancel()
+		}
+	}
+}
+
+func (s *Service) listenForRevocations() {
+	pubsub := s.redisClient.Subscribe(s.ctx, sessionsChannel)
+	defer pubsub.Close()
+
+	for msg := range pubsub.Channel() {
+		var revocation revocationMessage
+		if err := json.Unmarshal([]byte(msg.Payload), &revocation); err != nil {
+			continue
+		}
+		s.cancelSessions(revocation.UserID, revocation.JTI)
+	}
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository interface
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	Get(id uint) (*models.APIKey, error)
+	GetByHash(hash string) (*models.APIKey, error)
+	ListForUser(userID uint) ([]models.APIKey, error)
+	Revoke(id uint, at time.Time) error
+	TouchLastUsed(id uint, at time.Time, ip string) error
+}
+
+// GormAPIKeyRepository implementation
+type GormAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *GormAPIKeyRepository {
+	return &GormAPIKeyRepository{db: db}
+}
+
+func (r *GormAPIKeyRepository) Create(key *models.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *GormAPIKeyRepository) Get(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.Preload("User").Where("id = ?", id).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetByHash loads the key with its service account
+func (r *GormAPIKeyRepository) GetByHash(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.Preload("User").Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *GormAPIKeyRepository) ListForUser(userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *GormAPIKeyRepository) Revoke(id uint, at time.Time) error {
+	return r.db.Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+func (r *GormAPIKeyRepository) TouchLastUsed(id uint, at time.Time, ip string) error {
+	return r.db.Model(&models.APIKey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"last_used_at": at, "last_used_ip": ip}).Error
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"backend/models"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -13,13 +14,43 @@ type DrawingRepository interface {
 	GetForUpdate(id uint) (*models.Drawing, error)
 	Update(drawing *models.Drawing, updates map[string]interface{}) error
 	CreateWorkflowLog(log models.WorkflowLog) error
+	GetWorkflowLogs(drawingID uint, limit int) ([]models.WorkflowLog, error)
 	GetByProject(projectID uint) ([]models.Drawing, error)
+	GetByOrganization(organizationID uint) ([]models.Drawing, error)
+	GetForMember(userID uint) ([]models.Drawing, error)
+	GetAssignedForUpdate(projectID uint, userID uint) ([]models.Drawing, error)
+	GetProjectForShare(projectID uint) (*models.Project, error)
+	GetProjectStats(projectID uint, weekStart time.Time, monthStart time.Time) (*ProjectStats, error)
 	Create(drawing *models.Drawing) error
+	Delete(drawing *models.Drawing) error
 
 	// Transaction support
 	RunTransaction(fn func(repo DrawingRepository) error) error
 }
 
+type StageStats struct {
+	Stage         models.Stage `json:"stage"`
+	Count         int64        `json:"count"`
+	AvgAgeSeconds float64      `json:"avg_age_seconds"` // Average time spent in the current stage so far
+}
+
+type AssigneeStats struct {
+	AssigneeID uint   `json:"assignee_id"`
+	Username   string `json:"username"`
+	Count      int64  `json:"count"`
+}
+
+type ProjectStats struct {
+	Total             int64           `json:"total"`
+	Claimed           int64           `json:"claimed"`
+	Pooled            int64           `json:"pooled"` // Unassigned and not yet approved
+	AvgAgeSeconds     float64         `json:"avg_age_seconds"`
+	ApprovedThisWeek  int64           `json:"approved_this_week"`
+	ApprovedThisMonth int64           `json:"approved_this_month"`
+	ByStage           []StageStats    `json:"by_stage"`
+	ByAssignee        []AssigneeStats `json:"by_assignee"`
+}
+
 // GormDrawingRepository implementation
 type GormDrawingRepository struct {
 	db *gorm.DB
@@ -63,20 +94,125 @@ func (r *GormDrawingRepository) CreateWorkflowLog(log models.WorkflowLog) error
 	return r.db.Create(&log).Error
 }
 
+// GetWorkflowLogs returns the most recent transitions of a drawing, newest first
+func (r *GormDrawingRepository) GetWorkflowLogs(drawingID uint, limit int) ([]models.WorkflowLog, error) {
+	var logs []models.WorkflowLog
+	err := r.db.Preload("Actor").
+		Where("drawing_id = ?", drawingID).
+		Order("timestamp DESC").
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
 func (r *GormDrawingRepository) GetByProject(projectID uint) ([]models.Drawing, error) {
 	var drawings []models.Drawing
-	query := r.db.Preload("Assignee")
+	err := r.db.Preload("Assignee").Where("project_id = ?", projectID).Find(&drawings).Error
+	return drawings, err
+}
+
+// GetByOrganization returns the drawings of the organization's projects that are not archived
+func (r *GormDrawingRepository) GetByOrganization(organizationID uint) ([]models.Drawing, error) {
+	return r.getInLiveProjects(r.db.Model(&models.Project{}).
+		Select("projects.id").
+		Where("projects.organization_id = ?", organizationID))
+}
+
+// GetForMember returns the drawings of the user's projects that are not archived
+func (r *GormDrawingRepository) GetForMember(userID uint) ([]models.Drawing, error) {
+	return r.getInLiveProjects(r.db.Model(&models.Project{}).
+		Select("projects.id").
+		Joins("JOIN project_members ON project_members.project_id = projects.id").
+		Where("project_members.user_id = ?", userID))
+}
+
+// getInLiveProjects loads the drawings of the selected projects for the listings without a
+// project, leaving out archived projects as the project listings do
+func (r *GormDrawingRepository) getInLiveProjects(projectIDs *gorm.DB) ([]models.Drawing, error) {
+	var drawings []models.Drawing
+	err := r.db.Preload("Assignee").
+		Where("project_id IN (?)", projectIDs.Where("projects.archived_at IS NULL")).
+		Order("id").
+		Find(&drawings).Error
+	return drawings, err
+}
+
+// GetAssignedForUpdate locks the drawings held by a user, in ID order to match batch locking.
+// A projectID of 0 covers all projects. Drawings of archived projects are left out. Only the
+// drawing rows are locked: workflow actions lock a drawing before its project, so locking the
+// projects here as well could deadlock with them.
+func (r *GormDrawingRepository) GetAssignedForUpdate(projectID uint, userID uint) ([]models.Drawing, error) {
+	var drawings []models.Drawing
+	query := r.db.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "drawings"}}).
+		Joins("JOIN projects ON projects.id = drawings.project_id AND projects.archived_at IS NULL").
+		Where("drawings.assignee_id = ?", userID)
 	if projectID != 0 {
-		query = query.Where("project_id = ?", projectID)
+		query = query.Where("drawings.project_id = ?", projectID)
 	}
-	err := query.Find(&drawings).Error
+	err := query.Order("drawings.id").Find(&drawings).Error
 	return drawings, err
 }
 
+// GetProjectForShare reads a drawing's project and keeps it from being archived or
+// unarchived until the transaction ends
+func (r *GormDrawingRepository) GetProjectForShare(projectID uint) (*models.Project, error) {
+	var project models.Project
+	if err := r.db.Clauses(clause.Locking{Strength: "SHARE"}).Where("id = ?", projectID).First(&project).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// GetProjectStats aggregates a project's drawings in the database; no rows are loaded
+func (r *GormDrawingRepository) GetProjectStats(projectID uint, weekStart time.Time, monthStart time.Time) (*ProjectStats, error) {
+	stats := ProjectStats{ByStage: []StageStats{}, ByAssignee: []AssigneeStats{}}
+
+	err := r.db.Model(&models.Drawing{}).
+		Select(`COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE assignee_id IS NOT NULL) AS claimed,
+			COUNT(*) FILTER (WHERE assignee_id IS NULL AND current_stage <> ?) AS pooled,
+			COALESCE(AVG(EXTRACT(EPOCH FROM NOW() - stage_changed_at)), 0) AS avg_age_seconds,
+			COUNT(*) FILTER (WHERE current_stage = ? AND stage_changed_at >= ?) AS approved_this_week,
+			COUNT(*) FILTER (WHERE current_stage = ? AND stage_changed_at >= ?) AS approved_this_month`,
+			models.StageApproved, models.StageApproved, weekStart, models.StageApproved, monthStart).
+		Where("project_id = ?", projectID).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Model(&models.Drawing{}).
+		Select("current_stage AS stage, COUNT(*) AS count, AVG(EXTRACT(EPOCH FROM NOW() - stage_changed_at)) AS avg_age_seconds").
+		Where("project_id = ?", projectID).
+		Group("current_stage").
+		Order("current_stage").
+		Scan(&stats.ByStage).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Model(&models.Drawing{}).
+		Select("drawings.assignee_id, users.username, COUNT(*) AS count").
+		Joins("JOIN users ON users.id = drawings.assignee_id").
+		Where("drawings.project_id = ?", projectID).
+		Group("drawings.assignee_id, users.username").
+		Order("count DESC").
+		Scan(&stats.ByAssignee).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
 func (r *GormDrawingRepository) Create(drawing *models.Drawing) error {
 	return r.db.Create(drawing).Error
 }
 
+func (r *GormDrawingRepository) Delete(drawing *models.Drawing) error {
+	return r.db.Delete(drawing).Error
+}
+
 func (r *GormDrawingRepository) RunTransaction(fn func(repo DrawingRepository) error) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		txRepo := NewDrawingRepository(tx)
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"backend/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InvitationRepository interface
+type InvitationRepository interface {
+	Create(invitation *models.Invitation) error
+	Get(id uint) (*models.Invitation, error)
+	GetByHashForUpdate(hash string) (*models.Invitation, error)
+	ListOpen(organizationID uint) ([]models.Invitation, error)
+	MarkUsed(id uint, userID uint, at time.Time) error
+	Revoke(id uint, at time.Time) error
+
+	// Repositories sharing the same transaction
+	Users() UserRepository
+	Projects() ProjectRepository
+
+	// Transaction support
+	RunTransaction(fn func(repo InvitationRepository) error) error
+}
+
+// GormInvitationRepository implementation
+type GormInvitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) *GormInvitationRepository {
+	return &GormInvitationRepository{db: db}
+}
+
+func (r *GormInvitationRepository) Create(invitation *models.Invitation) error {
+	return r.db.Create(invitation).Error
+}
+
+func (r *GormInvitationRepository) Get(id uint) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := r.db.Preload("Memberships").Where("id = ?", id).First(&invitation).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *GormInvitationRepository) GetByHashForUpdate(hash string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", hash).
+		First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("invitation_id = ?", invitation.ID).Find(&invitation.Memberships).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// ListOpen returns the invitations that can still be accepted
+func (r *GormInvitationRepository) ListOpen(organizationID uint) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	err := r.db.Preload("Memberships").
+		Where("organization_id = ? AND used_at IS NULL AND revoked_at IS NULL AND expires_at > ?", organizationID, time.Now()).
+		Order("created_at DESC").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+func (r *GormInvitationRepository) MarkUsed(id uint, userID uint, at time.Time) error {
+	return r.db.Model(&models.Invitation{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"used_at":    at,
+		"used_by_id": userID,
+	}).Error
+}
+
+func (r *GormInvitationRepository) Revoke(id uint, at time.Time) error {
+	return r.db.Model(&models.Invitation{}).Where("id = ?", id).Update("revoked_at", at).Error
+}
+
+func (r *GormInvitationRepository) Users() UserRepository {
+	return NewUserRepository(r.db)
+}
+
+func (r *GormInvitationRepository) Projects() ProjectRepository {
+	return NewProjectRepository(r.db)
+}
+
+func (r *GormInvitationRepository) RunTransaction(fn func(repo InvitationRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := NewInvitationRepository(tx)
+		return fn(txRepo)
+	})
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"backend/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MFARepository interface
+type MFARepository interface {
+	GetCredential(userID uint) (*models.TOTPCredential, error)
+	GetCredentialForUpdate(userID uint) (*models.TOTPCredential, error)
+	// SaveCredential replaces the user's credential
+	SaveCredential(credential *models.TOTPCredential) error
+	UpdateCredential(credential *models.TOTPCredential, updates map[string]interface{}) error
+	DeleteCredential(userID uint) error
+
+	// ReplaceRecoveryCodes drops the user's recovery codes and stores new ones
+	ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode) error
+	CountUnusedRecoveryCodes(userID uint) (int64, error)
+	// UseRecoveryCode marks an unused code as used and reports whether there was one
+	UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error)
+
+	// Users returns a user repository sharing this repository's connection or transaction
+	Users() UserRepository
+
+	// Transaction support
+	RunTransaction(fn func(repo MFARepository) error) error
+}
+
+// GormMFARepository implementation
+type GormMFARepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) *GormMFARepository {
+	return &GormMFARepository{db: db}
+}
+
+func (r *GormMFARepository) GetCredential(userID uint) (*models.TOTPCredential, error) {
+	var credential models.TOTPCredential
+	if err := r.db.Where("user_id = ?", userID).First(&credential).Error; err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *GormMFARepository) GetCredentialForUpdate(userID uint) (*models.TOTPCredential, error) {
+	var credential models.TOTPCredential
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&credential).Error; err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *GormMFARepository) SaveCredential(credential *models.TOTPCredential) error {
+	if err := r.DeleteCredential(credential.UserID); err != nil {
+		return err
+	}
+	return r.db.Create(credential).Error
+}
+
+func (r *GormMFARepository) UpdateCredential(credential *models.TOTPCredential, updates map[string]interface{}) error {
+	return r.db.Model(credential).Updates(updates).Error
+}
+
+func (r *GormMFARepository) DeleteCredential(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.TOTPCredential{}).Error
+}
+
+func (r *GormMFARepository) ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode) error {
+	if err := r.db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return r.db.Create(&codes).Error
+}
+
+func (r *GormMFARepository) CountUnusedRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *GormMFARepository) UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *GormMFARepository) Users() UserRepository {
+	return NewUserRepository(r.db)
+}
+
+func (r *GormMFARepository) RunTransaction(fn func(repo MFARepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := NewMFARepository(tx)
+		return fn(txRepo)
+	})
+}
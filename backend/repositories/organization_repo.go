@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// OrganizationRepository interface
+type OrganizationRepository interface {
+	Get(id uint) (*models.Organization, error)
+	GetBySlug(slug string) (*models.Organization, error)
+	List() ([]models.Organization, error)
+	Create(organization *models.Organization) error
+}
+
+// GormOrganizationRepository implementation
+type GormOrganizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(db *gorm.DB) *GormOrganizationRepository {
+	return &GormOrganizationRepository{db: db}
+}
+
+func (r *GormOrganizationRepository) Get(id uint) (*models.Organization, error) {
+	var organization models.Organization
+	if err := r.db.Where("id = ?", id).First(&organization).Error; err != nil {
+		return nil, err
+	}
+	return &organization, nil
+}
+
+func (r *GormOrganizationRepository) GetBySlug(slug string) (*models.Organization, error) {
+	var organization models.Organization
+	if err := r.db.Where("slug = ?", slug).First(&organization).Error; err != nil {
+		return nil, err
+	}
+	return &organization, nil
+}
+
+func (r *GormOrganizationRepository) List() ([]models.Organization, error) {
+	var organizations []models.Organization
+	err := r.db.Order("name").Find(&organizations).Error
+	return organizations, err
+}
+
+func (r *GormOrganizationRepository) Create(organization *models.Organization) error {
+	return r.db.Create(organization).Error
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"backend/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PasswordRepository interface
+type PasswordRepository interface {
+	AddHistory(entry *models.PasswordHistory) error
+	RecentHistory(userID uint, limit int) ([]models.PasswordHistory, error)
+	CreateResetToken(token *models.PasswordResetToken) error
+	GetResetTokenForUpdate(hash string) (*models.PasswordResetToken, error)
+	// UseResetTokens marks every open reset token of the user as used
+	UseResetTokens(userID uint, at time.Time) error
+
+	// Users returns a user repository sharing this repository's connection or transaction
+	Users() UserRepository
+
+	// Transaction support
+	RunTransaction(fn func(repo PasswordRepository) error) error
+}
+
+// GormPasswordRepository implementation
+type GormPasswordRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordRepository(db *gorm.DB) *GormPasswordRepository {
+	return &GormPasswordRepository{db: db}
+}
+
+func (r *GormPasswordRepository) AddHistory(entry *models.PasswordHistory) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *GormPasswordRepository) RecentHistory(userID uint, limit int) ([]models.PasswordHistory, error) {
+	var entries []models.PasswordHistory
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+func (r *GormPasswordRepository) CreateResetToken(token *models.PasswordResetToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *GormPasswordRepository) GetResetTokenForUpdate(hash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *GormPasswordRepository) UseResetTokens(userID uint, at time.Time) error {
+	return r.db.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", at).Error
+}
+
+func (r *GormPasswordRepository) Users() UserRepository {
+	return NewUserRepository(r.db)
+}
+
+func (r *GormPasswordRepository) RunTransaction(fn func(repo PasswordRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := NewPasswordRepository(tx)
+		return fn(txRepo)
+	})
+}
@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MemberProject is a project together with the caller's role in it
+type MemberProject struct {
+	models.Project
+	Role models.UserRole `json:"role"`
+}
+
+// ProjectRepository interface
+type ProjectRepository interface {
+	Get(id uint) (*models.Project, error)
+	GetForUpdate(id uint) (*models.Project, error)
+	List(organizationID uint, includeArchived bool) ([]models.Project, error)
+	ListTemplates(organizationID uint) ([]models.Project, error)
+	OrganizationOf(projectID uint) (uint, error)
+	ListForUser(userID uint, includeArchived bool) ([]MemberProject, error)
+	Create(project *models.Project) error
+	Update(project *models.Project, updates map[string]interface{}) error
+	Delete(project *models.Project) error
+	GetMembers(projectID uint) ([]models.ProjectMember, error)
+	GetMember(projectID uint, userID uint) (*models.ProjectMember, error)
+	GetMembershipsBySource(userID uint, source models.MemberSource) ([]models.ProjectMember, error)
+	AddMember(member *models.ProjectMember) error
+	UpdateMember(member *models.ProjectMember, updates map[string]interface{}) error
+	RemoveMember(member *models.ProjectMember) error
+	CountOwnersForUpdate(projectID uint) (int64, error)
+
+	// Drawings returns a drawing repository sharing this repository's connection or transaction
+	Drawings() DrawingRepository
+	// Users returns a user repository sharing this repository's connection or transaction
+	Users() UserRepository
+
+	// Transaction support
+	RunTransaction(fn func(repo ProjectRepository) error) error
+}
+
+// GormProjectRepository implementation
+type GormProjectRepository struct {
+	db *gorm.DB
+}
+
+func NewProjectRepository(db *gorm.DB) *GormProjectRepository {
+	return &GormProjectRepository{db: db}
+}
+
+func (r *GormProjectRepository) Get(id uint) (*models.Project, error) {
+	var project models.Project
+	if err := r.db.Where("id = ?", id).First(&project).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// GetForUpdate locks the project row until the transaction ends
+func (r *GormProjectRepository) GetForUpdate(id uint) (*models.Project, error) {
+	var project models.Project
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&project).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (r *GormProjectRepository) List(organizationID uint, includeArchived bool) ([]models.Project, error) {
+	var projects []models.Project
+	query := r.db.Where("organization_id = ?", organizationID).Order("name")
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+	err := query.Find(&projects).Error
+	return projects, err
+}
+
+func (r *GormProjectRepository) ListTemplates(organizationID uint) ([]models.Project, error) {
+	var projects []models.Project
+	err := r.db.Where("organization_id = ? AND is_template = ? AND archived_at IS NULL", organizationID, true).Order("name").Find(&projects).Error
+	return projects, err
+}
+
+// OrganizationOf returns the organization owning a project, including soft-deleted ones
+func (r *GormProjectRepository) OrganizationOf(projectID uint) (uint, error) {
+	var project models.Project
+	if err := r.db.Unscoped().Select("organization_id").Where("id = ?", projectID).First(&project).Error; err != nil {
+		return 0, err
+	}
+	return project.OrganizationID, nil
+}
+
+func (r *GormProjectRepository) ListForUser(userID uint, includeArchived bool) ([]MemberProject, error) {
+	var projects []MemberProject
+	query := r.db.Model(&models.Project{}).
+		Select("projects.*, project_members.role").
+		Joins("JOIN project_members ON project_members.project_id = projects.id").
+		Where("project_members.user_id = ?", userID).
+		Order("projects.name")
+	if !includeArchived {
+		query = query.Where("projects.archived_at IS NULL")
+	}
+	err := query.Scan(&projects).Error
+	return projects, err
+}
+
+func (r *GormProjectRepository) Create(project *models.Project) error {
+	return r.db.Create(project).Error
+}
+
+func (r *GormProjectRepository) Update(project *models.Project, updates map[string]interface{}) error {
+	return r.db.Model(project).Updates(updates).Error
+}
+
+// Delete soft-deletes the project and its drawings. Memberships are kept so the project can be restored.
+func (r *GormProjectRepository) Delete(project *models.Project) error {
+	if err := r.db.Where("project_id = ?", project.ID).Delete(&models.Drawing{}).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(project).Error
+}
+
+func (r *GormProjectRepository) GetMembers(projectID uint) ([]models.ProjectMember, error) {
+	var members []models.ProjectMember
+	err := r.db.Preload("User").Where("project_id = ?", projectID).Order("joined_at").Find(&members).Error
+	return members, err
+}
+
+func (r *GormProjectRepository) GetMember(projectID uint, userID uint) (*models.ProjectMember, error) {
+	var member models.ProjectMember
+	if err := r.db.Where("project_id = ? AND user_id = ?", projectID, userID).First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// GetMembershipsBySource lists the user's memberships across projects that were granted the given way
+func (r *GormProjectRepository) GetMembershipsBySource(userID uint, source models.MemberSource) ([]models.ProjectMember, error) {
+	var members []models.ProjectMember
+	err := r.db.Where("user_id = ? AND source = ?", userID, source).Order("project_id").Find(&members).Error
+	return members, err
+}
+
+func (r *GormProjectRepository) AddMember(member *models.ProjectMember) error {
+	return r.db.Create(member).Error
+}
+
+func (r *GormProjectRepository) UpdateMember(member *models.ProjectMember, updates map[string]interface{}) error {
+	return r.db.Model(member).
+		Where("project_id = ? AND user_id = ?", member.ProjectID, member.UserID).
+		Updates(updates).Error
+}
+
+func (r *GormProjectRepository) RemoveMember(member *models.ProjectMember) error {
+	return r.db.Where("project_id = ? AND user_id = ?", member.ProjectID, member.UserID).
+		Delete(&models.ProjectMember{}).Error
+}
+
+// CountOwnersForUpdate counts a project's owners and locks their rows until the transaction
+// ends, so that concurrent demotions see each other and cannot remove the last owner.
+// Postgres cannot lock an aggregate, so the rows are selected and counted here.
+func (r *GormProjectRepository) CountOwnersForUpdate(projectID uint) (int64, error) {
+	var userIDs []uint
+	err := r.db.Model(&models.ProjectMember{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("project_id = ? AND is_owner = ?", projectID, true).
+		Order("user_id").
+		Pluck("user_id", &userIDs).Error
+	return int64(len(userIDs)), err
+}
+
+func (r *GormProjectRepository) Drawings() DrawingRepository {
+	return NewDrawingRepository(r.db)
+}
+
+func (r *GormProjectRepository) Users() UserRepository {
+	return NewUserRepository(r.db)
+}
+
+func (r *GormProjectRepository) RunTransaction(fn func(repo ProjectRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := NewProjectRepository(tx)
+		return fn(txRepo)
+	})
+}
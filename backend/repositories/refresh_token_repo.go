@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"backend/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RefreshTokenRepository interface
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) error
+	GetByHashForUpdate(hash string) (*models.RefreshToken, error)
+	MarkUsed(id uint, at time.Time) error
+	RevokeFamily(familyID string, at time.Time) error
+	RevokeForUser(userID uint, at time.Time) error
+
+	// Transaction support
+	RunTransaction(fn func(repo RefreshTokenRepository) error) error
+}
+
+// GormRefreshTokenRepository implementation
+type GormRefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *GormRefreshTokenRepository {
+	return &GormRefreshTokenRepository{db: db}
+}
+
+func (r *GormRefreshTokenRepository) Create(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *GormRefreshTokenRepository) GetByHashForUpdate(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *GormRefreshTokenRepository) MarkUsed(id uint, at time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).Where("id = ?", id).Update("used_at", at).Error
+}
+
+func (r *GormRefreshTokenRepository) RevokeFamily(familyID string, at time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+func (r *GormRefreshTokenRepository) RevokeForUser(userID uint, at time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
+
+func (r *GormRefreshTokenRepository) RunTransaction(fn func(repo RefreshTokenRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := NewRefreshTokenRepository(tx)
+		return fn(txRepo)
+	})
+}
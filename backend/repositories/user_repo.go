@@ -2,14 +2,34 @@ package repositories
 
 import (
 	"backend/models"
+	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserRepository interface
 type UserRepository interface {
 	Create(user *models.User) error
-	GetByUsername(username string) (*models.User, error)
+	GetByUsername(organizationID uint, username string) (*models.User, error)
+	Get(id uint) (*models.User, error)
+	GetByExternalID(issuer string, subject string) (*models.User, error)
+	List(organizationID uint) ([]models.User, error)
+	ListByStatus(organizationID uint, status models.UserStatus) ([]models.User, error)
+	ListServiceAccounts(organizationID uint) ([]models.User, error)
+	Search(organizationID uint, filter UserFilter) ([]models.User, int64, error)
+	CountByRoleForUpdate(organizationID uint, role models.UserRole, status models.UserStatus) (int64, error)
+	Update(user *models.User, updates map[string]interface{}) error
+}
+
+// UserFilter narrows Search. Empty fields match everything; Query matches part of the
+// username or email, ignoring case.
+type UserFilter struct {
+	Query  string
+	Role   models.UserRole
+	Status models.UserStatus
+	Limit  int
+	Offset int
 }
 
 // GormUserRepository implementation
@@ -25,10 +45,90 @@ func (r *GormUserRepository) Create(user *models.User) error {
 	return r.db.Create(user).Error
 }
 
-func (r *GormUserRepository) GetByUsername(username string) (*models.User, error) {
+func (r *GormUserRepository) GetByUsername(organizationID uint, username string) (*models.User, error) {
 	var user models.User
-	if err := r.db.Where("username = ?", username).First(&user).Error; err != nil {
+	if err := r.db.Where("organization_id = ? AND username = ?", organizationID, username).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
+
+func (r *GormUserRepository) Get(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *GormUserRepository) GetByExternalID(issuer string, subject string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("external_issuer = ? AND external_subject = ?", issuer, subject).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *GormUserRepository) List(organizationID uint) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("organization_id = ?", organizationID).Order("username").Find(&users).Error
+	return users, err
+}
+
+func (r *GormUserRepository) ListByStatus(organizationID uint, status models.UserStatus) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("organization_id = ? AND status = ?", organizationID, status).Order("created_at").Find(&users).Error
+	return users, err
+}
+
+func (r *GormUserRepository) ListServiceAccounts(organizationID uint) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("organization_id = ? AND service_account = ?", organizationID, true).Order("username").Find(&users).Error
+	return users, err
+}
+
+func (r *GormUserRepository) Update(user *models.User, updates map[string]interface{}) error {
+	return r.db.Model(user).Updates(updates).Error
+}
+
+// Search returns one page of the matching users, ordered by username, and how many match in total
+func (r *GormUserRepository) Search(organizationID uint, filter UserFilter) ([]models.User, int64, error) {
+	query := r.db.Model(&models.User{}).Where("organization_id = ?", organizationID)
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(strings.ToLower(filter.Query)) + "%"
+		query = query.Where("LOWER(username) LIKE ? OR LOWER(email) LIKE ?", pattern, pattern)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []models.User
+	err := query.Order("username").Limit(filter.Limit).Offset(filter.Offset).Find(&users).Error
+	return users, total, err
+}
+
+// CountByRoleForUpdate counts the matching users and locks their rows until the transaction
+// ends, so that concurrent demotions see each other. Postgres cannot lock an aggregate,
+// so the rows are selected and counted here.
+func (r *GormUserRepository) CountByRoleForUpdate(organizationID uint, role models.UserRole, status models.UserStatus) (int64, error) {
+	var ids []uint
+	err := r.db.Model(&models.User{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("organization_id = ? AND role = ? AND status = ?", organizationID, role, status).
+		Order("id").
+		Pluck("id", &ids).Error
+	return int64(len(ids)), err
+}
+
+// escapeLike makes LIKE treat %, _ and the escape character itself literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,133 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"backend/auth"
+	"backend/realtime"
+	"backend/services"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type viewerKey struct{}
+
+type viewer struct {
+	userID uint
+	orgID  uint
+	role   string
+	jti    string
+}
+
+// permissions maps each RPC to the Casbin object and action its REST equivalent
+// requires. Methods mapped to an empty action only need a valid token here; they
+// act on a single project and are authorized against the caller's role in it.
+var permissions = map[string][2]string{
+	"/qc.v1.DrawingService/ListDrawings":   {"drawings", "view"},
+	"/qc.v1.DrawingService/GetDrawing":     {"drawings", "view"},
+	"/qc.v1.DrawingService/CreateDrawing":  {},
+	"/qc.v1.DrawingService/UpdateDrawing":  {},
+	"/qc.v1.DrawingService/DeleteDrawing":  {},
+	"/qc.v1.DrawingService/ClaimDrawing":   {},
+	"/qc.v1.DrawingService/SubmitDrawing":  {},
+	"/qc.v1.DrawingService/ReleaseDrawing": {},
+	"/qc.v1.DrawingService/RejectDrawing":  {},
+	"/qc.v1.DrawingService/WatchProject":   {"drawings", "view"},
+}
+
+// isPublic reports whether a method skips authentication (reflection only)
+func isPublic(method string) bool {
+	return strings.HasPrefix(method, "/grpc.reflection.")
+}
+
+// authenticate also loads the account, like the REST middleware: deactivated users are
+// rejected while their token is still valid, and the role comes from the account.
+func authenticate(ctx context.Context, method string, tokens *services.Tokens) (context.Context, error) {
+	if isPublic(method) {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization token is required")
+	}
+	parts := strings.Split(values[0], " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, status.Error(codes.Unauthenticated, "authorization token is required")
+	}
+
+	claims, err := auth.Authenticate(parts[1])
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+	user, err := tokens.ActiveUser(claims.UserID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	role := string(user.Role)
+
+	perm, ok := permissions[method]
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "permission denied")
+	}
+	if perm[1] != "" {
+		allowed, err := auth.Enforce(role, claims.OrganizationID, perm[0], perm[1])
+		if err != nil {
+			return nil, status.Error(codes.Internal, "error during authorization check")
+		}
+		if !allowed {
+			return nil, status.Error(codes.PermissionDenied, "permission denied")
+		}
+	}
+
+	return context.WithValue(ctx, viewerKey{}, viewer{userID: claims.UserID, orgID: claims.OrganizationID, role: role, jti: claims.ID}), nil
+}
+
+func viewerFrom(ctx context.Context) viewer {
+	v, _ := ctx.Value(viewerKey{}).(viewer)
+	return v
+}
+
+func UnaryAuthInterceptor(tokens *services.Tokens) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, info.FullMethod, tokens)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor also registers the stream with sessions, so revoking
+// the caller's token ends the stream
+func StreamAuthInterceptor(sessions realtime.SessionTracker, tokens *services.Tokens) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), info.FullMethod, tokens)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		if v := viewerFrom(ctx); v.userID != 0 {
+			untrack := sessions.TrackSession(v.userID, v.jti, cancel)
+			defer untrack()
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,241 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"backend/models"
+	qcv1 "backend/proto/qc/v1"
+	"backend/realtime"
+	"backend/services"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/microcosm-cc/bluemonday"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+type DrawingServer struct {
+	qcv1.UnimplementedDrawingServiceServer
+	service     *services.Drawing
+	access      *services.Access
+	broadcaster realtime.Broadcaster
+	ugcPolicy   *bluemonday.Policy
+}
+
+func NewDrawingServer(service *services.Drawing, access *services.Access, broadcaster realtime.Broadcaster) *DrawingServer {
+	return &DrawingServer{
+		service:     service,
+		access:      access,
+		broadcaster: broadcaster,
+		ugcPolicy:   bluemonday.UGCPolicy(),
+	}
+}
+
+// NewServer builds the gRPC server with auth interceptors and reflection enabled
+func NewServer(drawingServer *DrawingServer, sessions realtime.SessionTracker, tokens *services.Tokens) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryAuthInterceptor(tokens)),
+		grpc.StreamInterceptor(StreamAuthInterceptor(sessions, tokens)),
+	)
+	qcv1.RegisterDrawingServiceServer(srv, drawingServer)
+	reflection.Register(srv)
+	return srv
+}
+
+func (s *DrawingServer) ListDrawings(ctx context.Context, req *qcv1.ListDrawingsRequest) (*qcv1.ListDrawingsResponse, error) {
+	v := viewerFrom(ctx)
+	drawings, err := s.service.List(v.orgID, uint(req.ProjectId), v.userID, v.role)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &qcv1.ListDrawingsResponse{Drawings: make([]*qcv1.Drawing, len(drawings))}
+	for i := range drawings {
+		resp.Drawings[i] = toProto(&drawings[i])
+	}
+	return resp, nil
+}
+
+func (s *DrawingServer) GetDrawing(ctx context.Context, req *qcv1.GetDrawingRequest) (*qcv1.Drawing, error) {
+	v := viewerFrom(ctx)
+	drawing, err := s.service.Get(uint(req.Id), v.userID, v.role)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(drawing), nil
+}
+
+func (s *DrawingServer) CreateDrawing(ctx context.Context, req *qcv1.CreateDrawingRequest) (*qcv1.Drawing, error) {
+	if len(req.Title) < 3 || len(req.Title) > 100 || len(req.Description) > 500 || req.ProjectId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "title must be 3-100 characters, description at most 500, and project_id is required")
+	}
+
+	v := viewerFrom(ctx)
+	if _, err := s.access.Authorize(uint(req.ProjectId), v.userID, v.role, "drawings", "create"); err != nil {
+		return nil, toStatus(err)
+	}
+
+	drawing := models.Drawing{
+		Title:        s.ugcPolicy.Sanitize(req.Title),
+		Description:  s.ugcPolicy.Sanitize(req.Description),
+		ProjectID:    uint(req.ProjectId),
+		CurrentStage: models.StageUnassigned,
+		Version:      0,
+		AuthorID:     v.userID,
+	}
+
+	if err := s.service.Create(&drawing); err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(&drawing), nil
+}
+
+func (s *DrawingServer) UpdateDrawing(ctx context.Context, req *qcv1.UpdateDrawingRequest) (*qcv1.Drawing, error) {
+	if len(req.Title) < 3 || len(req.Title) > 100 || len(req.Description) > 500 {
+		return nil, status.Error(codes.InvalidArgument, "title must be 3-100 characters and description at most 500")
+	}
+
+	v := viewerFrom(ctx)
+	drawing, err := s.service.Update(uint(req.Id), req.ExpectedVersion, s.ugcPolicy.Sanitize(req.Title), s.ugcPolicy.Sanitize(req.Description), v.userID, v.role)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(drawing), nil
+}
+
+func (s *DrawingServer) DeleteDrawing(ctx context.Context, req *qcv1.DeleteDrawingRequest) (*emptypb.Empty, error) {
+	v := viewerFrom(ctx)
+	if err := s.service.Delete(uint(req.Id), v.userID, v.role); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *DrawingServer) ClaimDrawing(ctx context.Context, req *qcv1.WorkflowActionRequest) (*qcv1.Drawing, error) {
+	return s.workflowAction(ctx, req, models.ActionClaim)
+}
+
+func (s *DrawingServer) SubmitDrawing(ctx context.Context, req *qcv1.WorkflowActionRequest) (*qcv1.Drawing, error) {
+	return s.workflowAction(ctx, req, models.ActionSubmit)
+}
+
+func (s *DrawingServer) ReleaseDrawing(ctx context.Context, req *qcv1.WorkflowActionRequest) (*qcv1.Drawing, error) {
+	return s.workflowAction(ctx, req, models.ActionRelease)
+}
+
+func (s *DrawingServer) RejectDrawing(ctx context.Context, req *qcv1.WorkflowActionRequest) (*qcv1.Drawing, error) {
+	return s.workflowAction(ctx, req, models.ActionReject)
+}
+
+func (s *DrawingServer) workflowAction(ctx context.Context, req *qcv1.WorkflowActionRequest, action models.Action) (*qcv1.Drawing, error) {
+	v := viewerFrom(ctx)
+	drawing, err := s.service.ProcessWorkflowAction(uint(req.Id), v.userID, v.role, action)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(drawing), nil
+}
+
+func (s *DrawingServer) WatchProject(req *qcv1.WatchProjectRequest, stream grpc.ServerStreamingServer[qcv1.ProjectEvent]) error {
+	if req.ProjectId == 0 {
+		return status.Error(codes.InvalidArgument, "project_id is required")
+	}
+
+	projectID := uint(req.ProjectId)
+	v := viewerFrom(stream.Context())
+	if _, err := s.access.Authorize(projectID, v.userID, v.role, "drawings", "view"); err != nil {
+		return toStatus(err)
+	}
+
+	clientChan := s.broadcaster.Subscribe(projectID)
+	defer s.broadcaster.Unsubscribe(projectID, clientChan)
+
+	for {
+		select {
+		case msg, ok := <-clientChan:
+			if !ok {
+				return nil
+			}
+			var event struct {
+				Type    string          `json:"type"`
+				Payload json.RawMessage `json:"payload"`
+			}
+			if err := json.Unmarshal([]byte(msg), &event); err != nil {
+				continue
+			}
+			if err := stream.Send(&qcv1.ProjectEvent{Type: event.Type, PayloadJson: string(event.Payload)}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func toProto(d *models.Drawing) *qcv1.Drawing {
+	out := &qcv1.Drawing{
+		Id:           uint32(d.ID),
+		Title:        d.Title,
+		Description:  d.Description,
+		ProjectId:    uint32(d.ProjectID),
+		AuthorId:     uint32(d.AuthorID),
+		CurrentStage: string(d.CurrentStage),
+		Revision:     int32(d.Revision),
+		Version:      d.Version,
+		DrawingUrl:   d.DrawingURL,
+		CreatedAt:    timestamppb.New(d.CreatedAt),
+		UpdatedAt:    timestamppb.New(d.UpdatedAt),
+	}
+	if d.AssigneeID != nil {
+		assignee := uint32(*d.AssigneeID)
+		out.AssigneeId = &assignee
+	}
+	return out
+}
+
+func toStatus(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return status.Error(codes.AlreadyExists, "a drawing with this title already exists in this project")
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return status.Error(codes.NotFound, "drawing not found")
+	}
+
+	var domainErr *services.Error
+	if !errors.As(err, &domainErr) {
+		domainErr = services.Internal(err)
+	}
+	if domainErr.Kind == services.KindInternal {
+		log.Printf("internal error in gRPC call: %v", domainErr.Err)
+	}
+	return status.Error(grpcCode(domainErr.Kind), domainErr.Message)
+}
+
+func grpcCode(kind services.ErrorKind) codes.Code {
+	switch kind {
+	case services.KindInvalid:
+		return codes.InvalidArgument
+	case services.KindNotFound:
+		return codes.NotFound
+	case services.KindForbidden:
+		return codes.PermissionDenied
+	case services.KindConflict:
+		return codes.Aborted
+	case services.KindUnprocessable:
+		return codes.FailedPrecondition
+	case services.KindUnauthenticated:
+		return codes.Unauthenticated
+	case services.KindTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+
+	"backend/auth"
+	"backend/models"
+	"backend/repositories"
+)
+
+var (
+	ErrNotProjectMember = &Error{Kind: KindForbidden, Code: "NOT_PROJECT_MEMBER", Message: "You are not a member of this project"}
+	ErrPermissionDenied = &Error{Kind: KindForbidden, Code: "PERMISSION_DENIED", Message: "Permission denied"}
+	ErrProjectArchived  = &Error{Kind: KindConflict, Code: "PROJECT_ARCHIVED", Message: "This project is archived and read-only"}
+)
+
+// ProjectAccess resolves the role a user acts with inside a project
+type ProjectAccess interface {
+	ProjectRole(projectID uint, userID uint, globalRole string) (models.UserRole, error)
+	Authorize(projectID uint, userID uint, globalRole string, obj string, act string) (models.UserRole, error)
+}
+
+// Access resolves per-project roles from ProjectMember. Global admins act as
+// admin in every project of their organization; everyone else needs a membership row.
+// Projects of other organizations are reported as not found.
+type Access struct {
+	projects repositories.ProjectRepository
+	drawings repositories.DrawingRepository
+	users    repositories.UserRepository
+	auditor  Auditor
+}
+
+func NewAccess(projects repositories.ProjectRepository, drawings repositories.DrawingRepository, users repositories.UserRepository, auditor Auditor) *Access {
+	return &Access{
+		projects: projects,
+		drawings: drawings,
+		users:    users,
+		auditor:  auditor,
+	}
+}
+
+func (s *Access) ProjectRole(projectID uint, userID uint, globalRole string) (models.UserRole, error) {
+	role, _, err := s.resolve(projectID, userID, globalRole)
+	return role, err
+}
+
+// resolve returns the caller's role in the project and the project's organization
+func (s *Access) resolve(projectID uint, userID uint, globalRole string) (models.UserRole, uint, error) {
+	organizationID, err := s.projects.OrganizationOf(projectID)
+	if err != nil {
+		return "", 0, notFoundOr(err, ErrProjectNotFound)
+	}
+	if globalRole == string(models.RoleAdmin) {
+		user, err := s.users.Get(userID)
+		if err != nil {
+			return "", 0, notFoundOr(err, ErrUserNotFound)
+		}
+		if user.OrganizationID != organizationID {
+			return "", 0, ErrProjectNotFound
+		}
+		return models.RoleAdmin, organizationID, nil
+	}
+	member, err := s.projects.GetMember(projectID, userID)
+	if err != nil {
+		return "", 0, notFoundOr(err, ErrNotProjectMember)
+	}
+	return member.Role, organizationID, nil
+}
+
+// Authorize resolves the caller's project role and checks it against the Casbin policy
+// of the project's organization. Denials are sent to the audit log.
+func (s *Access) Authorize(projectID uint, userID uint, globalRole string, obj string, act string) (models.UserRole, error) {
+	role, organizationID, err := s.resolve(projectID, userID, globalRole)
+	if err != nil {
+		if errors.Is(err, ErrNotProjectMember) {
+			s.auditDenied(projectID, userID, obj, act, "not a project member")
+		}
+		if errors.Is(err, ErrProjectNotFound) {
+			s.auditDenied(projectID, userID, obj, act, "project not found in caller's organization")
+		}
+		return "", err
+	}
+	allowed, err := auth.Enforce(string(role), organizationID, obj, act)
+	if err != nil {
+		return "", Internal(err)
+	}
+	if !allowed {
+		s.auditDenied(projectID, userID, obj, act, "role "+string(role)+" not permitted")
+		return "", ErrPermissionDenied
+	}
+	return role, nil
+}
+
+func (s *Access) auditDenied(projectID uint, userID uint, obj string, act string, reason string) {
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:      "access.denied",
+		ActorID:   userID,
+		ProjectID: projectID,
+		Details: map[string]interface{}{
+			"object": obj,
+			"action": act,
+			"reason": reason,
+		},
+	})
+}
+
+// EnsureWritable refuses changes to drawings of archived projects. Changes made in a
+// transaction check this with the project locked instead (see ensureWritable).
+func (s *Access) EnsureWritable(projectID uint) error {
+	project, err := s.projects.Get(projectID)
+	if err != nil {
+		return notFoundOr(err, ErrProjectNotFound)
+	}
+	if project.ArchivedAt != nil {
+		return ErrProjectArchived
+	}
+	return nil
+}
+
+// DrawingProject returns the project a drawing belongs to
+func (s *Access) DrawingProject(drawingID uint) (uint, error) {
+	drawing, err := s.drawings.Get(drawingID)
+	if err != nil {
+		return 0, notFoundOr(err, ErrDrawingNotFound)
+	}
+	return drawing.ProjectID, nil
+}
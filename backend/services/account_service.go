@@ -0,0 +1,268 @@
+package services
+
+import (
+	"backend/models"
+	"backend/repositories"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RegistrationMode controls what POST /register does without an invitation
+type RegistrationMode string
+
+const (
+	RegistrationOpen     RegistrationMode = "open"     // Accounts are active immediately
+	RegistrationApproval RegistrationMode = "approval" // Accounts wait for an admin to approve them
+	RegistrationClosed   RegistrationMode = "closed"   // Invitation only
+)
+
+// SelfRegisteredRole is the role of accounts created without an invitation.
+// Anything more privileged has to be granted by an admin.
+const SelfRegisteredRole = models.RoleDrafter
+
+// DefaultInvitationTTL applies when an invitation is created without an expiry
+const DefaultInvitationTTL = 7 * 24 * time.Hour
+
+var (
+	ErrRegistrationClosed = &Error{Kind: KindForbidden, Code: "REGISTRATION_CLOSED", Message: "Registration requires an invitation"}
+	ErrInvalidInvitation  = &Error{Kind: KindInvalid, Code: "INVALID_INVITATION", Message: "Invitation is invalid, expired or already used"}
+	ErrInvitationNotFound = &Error{Kind: KindNotFound, Code: "INVITATION_NOT_FOUND", Message: "Invitation not found"}
+	ErrUsernameTaken      = &Error{Kind: KindConflict, Code: "USERNAME_TAKEN", Message: "Username already exists"}
+	ErrUserNotPending     = &Error{Kind: KindConflict, Code: "USER_NOT_PENDING", Message: "User is not waiting for approval"}
+)
+
+// InvitationSpec is what an admin fixes when inviting someone
+type InvitationSpec struct {
+	Role        models.UserRole
+	Note        string
+	TTL         time.Duration
+	Memberships []models.InvitationMembership
+}
+
+// Accounts handles registration, invitations and approval of new users
+type Accounts struct {
+	users       repositories.UserRepository
+	invitations repositories.InvitationRepository
+	auditor     Auditor
+	policy      *PasswordPolicy
+	mode        RegistrationMode
+}
+
+func NewAccounts(users repositories.UserRepository, invitations repositories.InvitationRepository, auditor Auditor, policy *PasswordPolicy, mode RegistrationMode) *Accounts {
+	return &Accounts{
+		users:       users,
+		invitations: invitations,
+		auditor:     auditor,
+		policy:      policy,
+		mode:        mode,
+	}
+}
+
+// Register creates an account. With an invitation token, the organization, role and
+// project memberships come from the invitation and the account is active at once.
+// Without one, the registration mode decides, and the account gets SelfRegisteredRole.
+func (s *Accounts) Register(organizationID uint, username string, email string, password string, invitationToken string) (*models.User, error) {
+	if err := s.policy.Check(password); err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	user := &models.User{
+		OrganizationID: organizationID,
+		Username:       username,
+		Email:          email,
+		PasswordHash:   string(hash),
+	}
+
+	if invitationToken != "" {
+		if err := s.accept(user, invitationToken); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	switch s.mode {
+	case RegistrationOpen:
+		user.Status = models.UserStatusActive
+	case RegistrationApproval:
+		user.Status = models.UserStatusPending
+	default:
+		return nil, ErrRegistrationClosed
+	}
+	user.Role = SelfRegisteredRole
+
+	if err := s.users.Create(user); err != nil {
+		return nil, userWriteError(err)
+	}
+	if user.Status == models.UserStatusPending {
+		s.auditor.ProduceAuditEvent(models.AuditEvent{
+			Type:         "user.registration_pending",
+			ActorID:      user.ID,
+			TargetUserID: user.ID,
+		})
+	}
+	return user, nil
+}
+
+// accept creates the user described by an invitation and uses it up, in one transaction
+func (s *Accounts) accept(user *models.User, token string) error {
+	var invitationID uint
+	err := s.invitations.RunTransaction(func(txRepo repositories.InvitationRepository) error {
+		invitation, err := txRepo.GetByHashForUpdate(hashToken(token))
+		if err != nil {
+			return notFoundOr(err, ErrInvalidInvitation)
+		}
+		if invitation.UsedAt != nil || invitation.RevokedAt != nil || time.Now().After(invitation.ExpiresAt) {
+			return ErrInvalidInvitation
+		}
+
+		user.OrganizationID = invitation.OrganizationID
+		user.Role = invitation.Role
+		user.Status = models.UserStatusActive
+		if err := txRepo.Users().Create(user); err != nil {
+			return userWriteError(err)
+		}
+		for _, membership := range invitation.Memberships {
+			err := txRepo.Projects().AddMember(&models.ProjectMember{
+				ProjectID: membership.ProjectID,
+				UserID:    user.ID,
+				Role:      membership.Role,
+				IsOwner:   membership.IsOwner,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		invitationID = invitation.ID
+		return txRepo.MarkUsed(invitation.ID, user.ID, time.Now())
+	})
+	if err != nil {
+		return domainError(err)
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "invitation.accepted",
+		ActorID:      user.ID,
+		TargetUserID: user.ID,
+		Details:      map[string]interface{}{"invitation_id": invitationID},
+	})
+	return nil
+}
+
+// Invite issues a single-use invitation into the actor's organization and returns
+// it with its token. The token is only ever shown here.
+func (s *Accounts) Invite(organizationID uint, actorID uint, spec InvitationSpec) (*models.Invitation, string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, "", Internal(err)
+	}
+	if spec.TTL <= 0 {
+		spec.TTL = DefaultInvitationTTL
+	}
+	invitation := &models.Invitation{
+		OrganizationID: organizationID,
+		TokenHash:      hashToken(token),
+		Role:           spec.Role,
+		Note:           spec.Note,
+		Memberships:    spec.Memberships,
+		CreatedByID:    actorID,
+		ExpiresAt:      time.Now().Add(spec.TTL),
+	}
+
+	err = s.invitations.RunTransaction(func(txRepo repositories.InvitationRepository) error {
+		for _, membership := range spec.Memberships {
+			owner, err := txRepo.Projects().OrganizationOf(membership.ProjectID)
+			if err != nil {
+				return notFoundOr(err, ErrProjectNotFound)
+			}
+			if owner != organizationID {
+				return ErrProjectNotFound
+			}
+		}
+		return txRepo.Create(invitation)
+	})
+	if err != nil {
+		return nil, "", domainError(err)
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:    "invitation.created",
+		ActorID: actorID,
+		Details: map[string]interface{}{
+			"invitation_id": invitation.ID,
+			"role":          invitation.Role,
+			"projects":      len(invitation.Memberships),
+		},
+	})
+	return invitation, token, nil
+}
+
+func (s *Accounts) ListInvitations(organizationID uint) ([]models.Invitation, error) {
+	invitations, err := s.invitations.ListOpen(organizationID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	return invitations, nil
+}
+
+func (s *Accounts) RevokeInvitation(id uint, organizationID uint, actorID uint) error {
+	invitation, err := s.invitations.Get(id)
+	if err != nil || invitation.OrganizationID != organizationID {
+		return notFoundOr(err, ErrInvitationNotFound)
+	}
+	if invitation.UsedAt != nil {
+		return ErrInvalidInvitation
+	}
+	if err := s.invitations.Revoke(id, time.Now()); err != nil {
+		return Internal(err)
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:    "invitation.revoked",
+		ActorID: actorID,
+		Details: map[string]interface{}{"invitation_id": id},
+	})
+	return nil
+}
+
+func (s *Accounts) ListPending(organizationID uint) ([]models.User, error) {
+	users, err := s.users.ListByStatus(organizationID, models.UserStatusPending)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	return users, nil
+}
+
+// Approve lets a self-registered user log in
+func (s *Accounts) Approve(userID uint, organizationID uint, actorID uint) (*models.User, error) {
+	user, err := s.users.Get(userID)
+	if err != nil || user.OrganizationID != organizationID {
+		return nil, notFoundOr(err, ErrUserNotFound)
+	}
+	if user.Status != models.UserStatusPending {
+		return nil, ErrUserNotPending
+	}
+	if err := s.users.Update(user, map[string]interface{}{"status": models.UserStatusActive}); err != nil {
+		return nil, Internal(err)
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "user.approved",
+		ActorID:      actorID,
+		TargetUserID: user.ID,
+	})
+	return user, nil
+}
+
+func userWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrUsernameTaken
+	}
+	return Internal(err)
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"backend/models"
+	"backend/repositories"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// apiKeyPrefix marks our keys, so they are easy to spot in leaked-secret scans
+const apiKeyPrefix = "qck_"
+
+const (
+	DefaultAPIKeyTTL = 90 * 24 * time.Hour
+	MaxAPIKeyTTL     = 365 * 24 * time.Hour
+
+	// lastUsedInterval limits how often using a key writes to the database
+	lastUsedInterval = time.Minute
+)
+
+var scopePattern = regexp.MustCompile(`^(\*|[a-z_]+:(\*|[a-z_]+))$`)
+
+var (
+	ErrInvalidAPIKey       = &Error{Kind: KindUnauthenticated, Code: "INVALID_API_KEY", Message: "Invalid, expired or revoked API key"}
+	ErrAPIKeyNotFound      = &Error{Kind: KindNotFound, Code: "API_KEY_NOT_FOUND", Message: "API key not found"}
+	ErrNotServiceAccount   = &Error{Kind: KindNotFound, Code: "SERVICE_ACCOUNT_NOT_FOUND", Message: "Service account not found"}
+	ErrInvalidScope        = &Error{Kind: KindInvalid, Code: "INVALID_SCOPE", Message: "Scopes must look like object:action, object:* or *"}
+	ErrInvalidAPIKeyExpiry = &Error{Kind: KindInvalid, Code: "INVALID_EXPIRY", Message: "API keys must expire within a year"}
+)
+
+// ScopesAllow reports whether API key scopes cover an object and action
+func ScopesAllow(scopes []string, obj string, act string) bool {
+	for _, scope := range scopes {
+		if scope == "*" || scope == obj+":*" || scope == obj+":"+act {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeys manages service accounts and the keys they authenticate with
+type APIKeys struct {
+	repo     repositories.APIKeyRepository
+	users    repositories.UserRepository
+	auditor  Auditor
+	sessions SessionTerminator
+}
+
+func NewAPIKeys(repo repositories.APIKeyRepository, users repositories.UserRepository, auditor Auditor, sessions SessionTerminator) *APIKeys {
+	return &APIKeys{
+		repo:     repo,
+		users:    users,
+		auditor:  auditor,
+		sessions: sessions,
+	}
+}
+
+// SessionID identifies requests made with a key, so revoking it cuts them off
+func (s *APIKeys) SessionID(key *models.APIKey) string {
+	return fmt.Sprintf("apikey:%d", key.ID)
+}
+
+// CreateServiceAccount adds a user that cannot log in and acts only through API keys.
+// Its role and project memberships bound what its keys can do.
+func (s *APIKeys) CreateServiceAccount(organizationID uint, actorID uint, username string, role models.UserRole) (*models.User, error) {
+	user := &models.User{
+		OrganizationID: organizationID,
+		Username:       username,
+		Role:           role,
+		Status:         models.UserStatusActive,
+		ServiceAccount: true,
+	}
+	if err := s.users.Create(user); err != nil {
+		return nil, userWriteError(err)
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "service_account.created",
+		ActorID:      actorID,
+		TargetUserID: user.ID,
+		Details:      map[string]interface{}{"role": role},
+	})
+	return user, nil
+}
+
+func (s *APIKeys) ListServiceAccounts(organizationID uint) ([]models.User, error) {
+	users, err := s.users.ListServiceAccounts(organizationID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	return users, nil
+}
+
+// CreateKey issues a key for a service account and returns it with the raw key, which is only shown here
+func (s *APIKeys) CreateKey(serviceAccountID uint, organizationID uint, actorID uint, name string, scopes []string, ttl time.Duration) (*models.APIKey, string, error) {
+	if _, err := s.serviceAccount(serviceAccountID, organizationID); err != nil {
+		return nil, "", err
+	}
+	for _, scope := range scopes {
+		if !scopePattern.MatchString(scope) {
+			return nil, "", ErrInvalidScope
+		}
+	}
+	if ttl == 0 {
+		ttl = DefaultAPIKeyTTL
+	}
+	if ttl < 0 || ttl > MaxAPIKeyTTL {
+		return nil, "", ErrInvalidAPIKeyExpiry
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", Internal(err)
+	}
+	raw := apiKeyPrefix + secret
+	key := &models.APIKey{
+		UserID:      serviceAccountID,
+		Name:        name,
+		Prefix:      raw[:len(apiKeyPrefix)+6],
+		KeyHash:     hashToken(raw),
+		Scopes:      scopes,
+		ExpiresAt:   time.Now().Add(ttl),
+		CreatedByID: actorID,
+	}
+	if err := s.repo.Create(key); err != nil {
+		return nil, "", Internal(err)
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "api_key.created",
+		ActorID:      actorID,
+		TargetUserID: serviceAccountID,
+		Details: map[string]interface{}{
+			"api_key_id": key.ID,
+			"scopes":     scopes,
+			"expires_at": key.ExpiresAt,
+		},
+	})
+	return key, raw, nil
+}
+
+func (s *APIKeys) ListKeys(serviceAccountID uint, organizationID uint) ([]models.APIKey, error) {
+	if _, err := s.serviceAccount(serviceAccountID, organizationID); err != nil {
+		return nil, err
+	}
+	keys, err := s.repo.ListForUser(serviceAccountID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	return keys, nil
+}
+
+// RevokeKey disables a key at once, including requests still streaming with it
+func (s *APIKeys) RevokeKey(serviceAccountID uint, keyID uint, organizationID uint, actorID uint) error {
+	key, err := s.repo.Get(keyID)
+	if err != nil || key.UserID != serviceAccountID || key.User.OrganizationID != organizationID {
+		return notFoundOr(err, ErrAPIKeyNotFound)
+	}
+	if err := s.repo.Revoke(key.ID, time.Now()); err != nil {
+		return Internal(err)
+	}
+	s.sessions.TerminateSessions(key.UserID, s.SessionID(key))
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "api_key.revoked",
+		ActorID:      actorID,
+		TargetUserID: key.UserID,
+		Details:      map[string]interface{}{"api_key_id": key.ID},
+	})
+	return nil
+}
+
+// Authenticate resolves a raw key to the key and its service account, and records its use
+func (s *APIKeys) Authenticate(raw string, ip string) (*models.APIKey, error) {
+	if !strings.HasPrefix(raw, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	key, err := s.repo.GetByHash(hashToken(raw))
+	if err != nil {
+		return nil, notFoundOr(err, ErrInvalidAPIKey)
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil || now.After(key.ExpiresAt) || key.User == nil ||
+		!key.User.ServiceAccount || key.User.Status != models.UserStatusActive {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedInterval || key.LastUsedIP != ip {
+		if err := s.repo.TouchLastUsed(key.ID, now, ip); err != nil {
+			log.Printf("Failed to record use of API key %d: %v", key.ID, err)
+		}
+	}
+	return key, nil
+}
+
+func (s *APIKeys) serviceAccount(id uint, organizationID uint) (*models.User, error) {
+	user, err := s.users.Get(id)
+	if err != nil || !user.ServiceAccount || user.OrganizationID != organizationID {
+		return nil, notFoundOr(err, ErrNotServiceAccount)
+	}
+	return user, nil
+}
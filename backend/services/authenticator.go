@@ -0,0 +1,130 @@
+package services
+
+import (
+	"backend/auth"
+	"backend/models"
+	"backend/repositories"
+	"errors"
+	"log"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidCredentials = &Error{Kind: KindUnauthenticated, Code: "INVALID_CREDENTIALS", Message: "Invalid username or password"}
+
+// dummyPasswordHash is compared against when there is no real hash, so a login for an
+// unknown user takes as long as one with a wrong password
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+// Authenticator checks a username and password within an organization. It returns
+// ErrInvalidCredentials when it does not know the user or the password is wrong.
+type Authenticator interface {
+	Authenticate(organizationID uint, username string, password string) (*models.User, error)
+}
+
+// AuthenticatorChain asks each authenticator in turn and returns the first user one accepts.
+// Domain errors other than ErrInvalidCredentials end the chain. Any other failure, such as
+// an unreachable directory, is logged and skipped, so local accounts keep working during an outage.
+type AuthenticatorChain []Authenticator
+
+func (chain AuthenticatorChain) Authenticate(organizationID uint, username string, password string) (*models.User, error) {
+	for _, authenticator := range chain {
+		user, err := authenticator.Authenticate(organizationID, username, password)
+		if err == nil {
+			return user, nil
+		}
+		if errors.Is(err, ErrInvalidCredentials) {
+			continue
+		}
+		var domainErr *Error
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		log.Printf("Authenticator %T failed: %v", authenticator, err)
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// LocalAuthenticator checks the bcrypt password stored on the user
+type LocalAuthenticator struct {
+	users repositories.UserRepository
+}
+
+func NewLocalAuthenticator(users repositories.UserRepository) *LocalAuthenticator {
+	return &LocalAuthenticator{users: users}
+}
+
+func (a *LocalAuthenticator) Authenticate(organizationID uint, username string, password string) (*models.User, error) {
+	user, err := a.users.GetByUsername(organizationID, username)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, Internal(err)
+	}
+	// Users from SSO or LDAP have no password hash, so this always fails for them
+	if user == nil || user.PasswordHash == "" {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// Directory checks a password against an external directory, implemented by auth.LDAPDirectory
+type Directory interface {
+	Authenticate(username string, password string) (*auth.Identity, error)
+}
+
+// LDAPAuthenticator binds against the directory and syncs the user, their role and
+// their mapped project memberships into the organization on every successful login
+type LDAPAuthenticator struct {
+	directory Directory
+	users     *externalUsers
+	auditor   Auditor
+}
+
+func NewLDAPAuthenticator(directory Directory, projects repositories.ProjectRepository, auditor Auditor, broadcaster Broadcaster, organizationID uint, mapping GroupMapping) *LDAPAuthenticator {
+	return &LDAPAuthenticator{
+		directory: directory,
+		users: &externalUsers{
+			projects:       projects,
+			auditor:        auditor,
+			broadcaster:    broadcaster,
+			organizationID: organizationID,
+			mapping:        mapping,
+		},
+		auditor: auditor,
+	}
+}
+
+func (a *LDAPAuthenticator) Authenticate(organizationID uint, username string, password string) (*models.User, error) {
+	if organizationID != a.users.organizationID {
+		return nil, ErrInvalidCredentials
+	}
+
+	identity, err := a.directory.Authenticate(username, password)
+	if errors.Is(err, auth.ErrLDAPInvalidCredentials) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, created, err := a.users.provision(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	a.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "auth.ldap_login",
+		ActorID:      user.ID,
+		TargetUserID: user.ID,
+		Details: map[string]interface{}{
+			"created": created,
+			"role":    user.Role,
+			"groups":  identity.Groups,
+		},
+	})
+	return user, nil
+}
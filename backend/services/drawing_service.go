@@ -3,12 +3,19 @@ package services
 import (
 	"backend/models"
 	"backend/repositories"
+	"errors"
 	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
 )
 
 // Auditor interface abstraction
 type Auditor interface {
 	ProduceAuditLog(workflowLog models.WorkflowLog)
+	ProduceAuditEvent(event models.AuditEvent)
 }
 
 // Broadcaster interface abstraction
@@ -18,90 +25,423 @@ type Broadcaster interface {
 
 type Drawing struct {
 	repo        repositories.DrawingRepository
+	roles       ProjectAccess
 	auditor     Auditor
 	broadcaster Broadcaster
 }
 
-func NewDrawing(repo repositories.DrawingRepository, auditor Auditor, broadcaster Broadcaster) *Drawing {
+func NewDrawing(repo repositories.DrawingRepository, roles ProjectAccess, auditor Auditor, broadcaster Broadcaster) *Drawing {
 	return &Drawing{
 		repo:        repo,
+		roles:       roles,
 		auditor:     auditor,
 		broadcaster: broadcaster,
 	}
 }
 
-func (s *Drawing) ProcessWorkflowAction(id uint, userID uint, userRole string, action models.Action) (*models.Drawing, error) {
-	var drawing models.Drawing
-	var workflowLog models.WorkflowLog
+// BatchItem is a single workflow action within a batch request.
+// ExpectedVersion must match the drawing's current version for the action to apply.
+type BatchItem struct {
+	DrawingID       uint
+	Action          models.Action
+	ExpectedVersion int64
+}
+
+// BatchResult reports the outcome of a BatchItem, in the order the items were submitted.
+type BatchResult struct {
+	DrawingID uint
+	Action    models.Action
+	Drawing   *models.Drawing
+	Err       error
+}
+
+// workflowOutcome carries what has to be published once the transaction commits.
+type workflowOutcome struct {
+	drawing     models.Drawing
+	workflowLog models.WorkflowLog
+	action      models.Action
+}
 
+// List returns the drawings of one project, or of every project the caller
+// belongs to when projectID is 0. Global admins see all projects of their organization.
+func (s *Drawing) List(organizationID uint, projectID uint, userID uint, globalRole string) ([]models.Drawing, error) {
+	var drawings []models.Drawing
+	var err error
+	switch {
+	case projectID != 0:
+		if _, err := s.roles.Authorize(projectID, userID, globalRole, "drawings", "view"); err != nil {
+			return nil, err
+		}
+		drawings, err = s.repo.GetByProject(projectID)
+	case globalRole == string(models.RoleAdmin):
+		drawings, err = s.repo.GetByOrganization(organizationID)
+	default:
+		drawings, err = s.repo.GetForMember(userID)
+	}
+	if err != nil {
+		return nil, Internal(err)
+	}
+	return drawings, nil
+}
+
+// Create stores a new drawing unless its project is archived
+func (s *Drawing) Create(drawing *models.Drawing) error {
 	err := s.repo.RunTransaction(func(txRepo repositories.DrawingRepository) error {
-		d, err := txRepo.GetForUpdate(id)
-		if err != nil {
+		if err := ensureWritable(txRepo, drawing.ProjectID); err != nil {
 			return err
 		}
-		drawing = *d
+		return txRepo.Create(drawing)
+	})
+	if err != nil {
+		return drawingWriteError(err)
+	}
+	return nil
+}
 
-		// Validation for Claim/Submit/Release/Reject
-		if action != models.ActionClaim {
-			// Admins can perform any action without being the assignee
-			if userRole != string(models.RoleAdmin) {
-				if drawing.AssigneeID == nil || *drawing.AssigneeID != userID {
-					return fmt.Errorf("drawing not assigned to user or unassigned")
-				}
-			}
+// Update changes a drawing's title and description unless it has moved past expectedVersion
+// or its project is archived. The caller needs drawings:update in the drawing's project.
+func (s *Drawing) Update(id uint, expectedVersion int64, title string, description string, userID uint, globalRole string) (*models.Drawing, error) {
+	var drawing *models.Drawing
+	err := s.repo.RunTransaction(func(txRepo repositories.DrawingRepository) error {
+		current, err := s.lockForChange(txRepo, id, userID, globalRole, "update")
+		if err != nil {
+			return err
 		}
+		if current.Version != expectedVersion {
+			return ErrVersionConflict.WithMessage("Version mismatch: expected %d, current %d", expectedVersion, current.Version)
+		}
+		updates := map[string]interface{}{
+			"title":       title,
+			"description": description,
+			"version":     current.Version + 1,
+		}
+		if err := txRepo.Update(current, updates); err != nil {
+			return err
+		}
+		drawing, err = txRepo.Get(id)
+		return err
+	})
+	if err != nil {
+		return nil, drawingWriteError(err)
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:      "drawing.updated",
+		ActorID:   userID,
+		ProjectID: drawing.ProjectID,
+		Details:   map[string]interface{}{"drawing_id": drawing.ID, "version": drawing.Version},
+	})
+	go s.broadcaster.BroadcastEvent(drawing.ProjectID, "DRAWING_UPDATED", drawing)
+	return drawing, nil
+}
 
-		nextStage, err := models.GetNextState(drawing.CurrentStage, action, models.UserRole(userRole))
+// Delete soft-deletes a drawing unless its project is archived. The caller needs
+// drawings:delete in the drawing's project.
+func (s *Drawing) Delete(id uint, userID uint, globalRole string) error {
+	var drawing *models.Drawing
+	err := s.repo.RunTransaction(func(txRepo repositories.DrawingRepository) error {
+		var err error
+		drawing, err = s.lockForChange(txRepo, id, userID, globalRole, "delete")
 		if err != nil {
 			return err
 		}
+		return txRepo.Delete(drawing)
+	})
+	if err != nil {
+		return domainError(err)
+	}
 
-		updates := map[string]interface{}{
-			"current_stage": nextStage,
-			"version":       drawing.Version + 1,
-		}
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:      "drawing.deleted",
+		ActorID:   userID,
+		ProjectID: drawing.ProjectID,
+		Details:   map[string]interface{}{"drawing_id": drawing.ID, "title": drawing.Title},
+	})
+	go s.broadcaster.BroadcastEvent(drawing.ProjectID, "DRAWING_DELETED", drawing)
+	return nil
+}
+
+// lockForChange locks a drawing, then checks the caller's role in its project and that the
+// project is not archived; the same order as workflow actions, so they cannot deadlock
+func (s *Drawing) lockForChange(txRepo repositories.DrawingRepository, id uint, userID uint, globalRole string, act string) (*models.Drawing, error) {
+	drawing, err := txRepo.GetForUpdate(id)
+	if err != nil {
+		return nil, notFoundOr(err, ErrDrawingNotFound)
+	}
+	if _, err := s.roles.Authorize(drawing.ProjectID, userID, globalRole, "drawings", act); err != nil {
+		return nil, err
+	}
+	if err := ensureWritable(txRepo, drawing.ProjectID); err != nil {
+		return nil, err
+	}
+	return drawing, nil
+}
+
+func drawingWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDrawingTitleTaken
+	}
+	return domainError(err)
+}
+
+// Get returns a single drawing if the caller may view its project
+func (s *Drawing) Get(id uint, userID uint, globalRole string) (*models.Drawing, error) {
+	drawing, err := s.repo.Get(id)
+	if err != nil {
+		return nil, notFoundOr(err, ErrDrawingNotFound)
+	}
+	if _, err := s.roles.Authorize(drawing.ProjectID, userID, globalRole, "drawings", "view"); err != nil {
+		return nil, err
+	}
+	return drawing, nil
+}
+
+// ProcessWorkflowAction applies one action. globalRole is the caller's system-wide role;
+// the role actually used is resolved from their membership in the drawing's project.
+func (s *Drawing) ProcessWorkflowAction(id uint, userID uint, globalRole string, action models.Action) (*models.Drawing, error) {
+	var outcome *workflowOutcome
+
+	err := s.repo.RunTransaction(func(txRepo repositories.DrawingRepository) error {
+		var err error
+		outcome, err = s.applyWorkflowAction(txRepo, id, nil, userID, globalRole, action)
+		return err
+	})
+
+	if err != nil {
+		return nil, domainError(err)
+	}
+
+	s.publish([]*workflowOutcome{outcome})
 
-		// Handle Assignee and Revision logic based on action
-		if action == models.ActionClaim {
-			if drawing.AssigneeID != nil {
-				return fmt.Errorf("drawing already claimed")
+	return &outcome.drawing, nil
+}
+
+// ProcessBatch runs several workflow actions in a single transaction.
+// Drawings are locked in ascending ID order regardless of the order they were
+// submitted in, so concurrent batches touching the same drawings cannot deadlock.
+// Permissions are checked per item against the caller's role in that drawing's project.
+//
+// In atomic mode the first failure rolls back the whole batch and is returned as
+// the error. Otherwise each item runs in its own savepoint and failures are only
+// reported in the per-item results.
+func (s *Drawing) ProcessBatch(items []BatchItem, userID uint, globalRole string, atomic bool) ([]BatchResult, error) {
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return items[order[a]].DrawingID < items[order[b]].DrawingID
+	})
+
+	results := make([]BatchResult, len(items))
+	outcomes := make([]*workflowOutcome, len(items))
+
+	err := s.repo.RunTransaction(func(txRepo repositories.DrawingRepository) error {
+		for _, i := range order {
+			item := items[i]
+			results[i] = BatchResult{DrawingID: item.DrawingID, Action: item.Action}
+			expected := item.ExpectedVersion
+
+			if atomic {
+				outcome, err := s.applyWorkflowAction(txRepo, item.DrawingID, &expected, userID, globalRole, item.Action)
+				if err != nil {
+					return batchItemError(item.DrawingID, err)
+				}
+				outcomes[i] = outcome
+				continue
 			}
-			updates["assignee_id"] = userID
-		} else if action == models.ActionSubmit || action == models.ActionReject {
-			// Submit or Reject increments the business revision
-			updates["assignee_id"] = nil
-			updates["revision"] = drawing.Revision + 1
-		} else {
-			// Release only clears assignee
-			updates["assignee_id"] = nil
-		}
 
-		if err := txRepo.Update(&drawing, updates); err != nil {
-			if err.Error() == "record not found" {
-				return fmt.Errorf("concurrent update detected")
+			// Nested transactions are savepoints, so a failed item does not abort the batch
+			err := txRepo.RunTransaction(func(itemRepo repositories.DrawingRepository) error {
+				outcome, err := s.applyWorkflowAction(itemRepo, item.DrawingID, &expected, userID, globalRole, item.Action)
+				outcomes[i] = outcome
+				return err
+			})
+			if err != nil {
+				outcomes[i] = nil
+				results[i].Err = domainError(err)
 			}
-			return err
 		}
+		return nil
+	})
 
-		workflowLog = models.WorkflowLog{
-			DrawingID: drawing.ID,
-			ActorID:   userID,
-			Action:    string(action),
-			FromStage: drawing.CurrentStage,
-			ToStage:   nextStage,
+	if err != nil {
+		return nil, domainError(err)
+	}
+
+	var committed []*workflowOutcome
+	for i, outcome := range outcomes {
+		if outcome != nil {
+			results[i].Drawing = &outcome.drawing
+			committed = append(committed, outcome)
 		}
-		return txRepo.CreateWorkflowLog(workflowLog)
-	})
+	}
+	s.publish(committed)
+
+	return results, nil
+}
+
+// applyWorkflowAction performs a single transition inside an open transaction.
+// The caller acts with their role in the drawing's project, not their global role.
+// If expectedVersion is set, the action is refused when the drawing has moved on.
+func (s *Drawing) applyWorkflowAction(txRepo repositories.DrawingRepository, id uint, expectedVersion *int64, userID uint, globalRole string, action models.Action) (*workflowOutcome, error) {
+	d, err := txRepo.GetForUpdate(id)
+	if err != nil {
+		return nil, notFoundOr(err, ErrDrawingNotFound)
+	}
+	drawing := *d
 
+	role, err := s.roles.Authorize(drawing.ProjectID, userID, globalRole, "drawings", string(action))
 	if err != nil {
 		return nil, err
 	}
+	if err := ensureWritable(txRepo, drawing.ProjectID); err != nil {
+		return nil, err
+	}
+
+	if expectedVersion != nil && drawing.Version != *expectedVersion {
+		return nil, ErrVersionConflict.WithMessage("Version mismatch: expected %d, current %d", *expectedVersion, drawing.Version)
+	}
+
+	// Validation for Claim/Submit/Release/Reject
+	if action != models.ActionClaim {
+		// Admins can perform any action without being the assignee
+		if role != models.RoleAdmin {
+			if drawing.AssigneeID == nil || *drawing.AssigneeID != userID {
+				return nil, ErrNotAssignee
+			}
+		}
+	}
+
+	nextStage, err := models.GetNextState(drawing.CurrentStage, action, role)
+	if err != nil {
+		return nil, workflowError(err)
+	}
+
+	updates := map[string]interface{}{
+		"current_stage": nextStage,
+		"version":       drawing.Version + 1,
+	}
+	if nextStage != drawing.CurrentStage {
+		updates["stage_changed_at"] = time.Now()
+	}
+
+	// Handle Assignee and Revision logic based on action
+	if action == models.ActionClaim {
+		if drawing.AssigneeID != nil {
+			return nil, ErrAlreadyClaimed
+		}
+		updates["assignee_id"] = userID
+	} else if action == models.ActionSubmit || action == models.ActionReject {
+		// Submit or Reject increments the business revision
+		updates["assignee_id"] = nil
+		updates["revision"] = drawing.Revision + 1
+	} else {
+		// Release only clears assignee
+		updates["assignee_id"] = nil
+	}
+
+	if err := txRepo.Update(&drawing, updates); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVersionConflict
+		}
+		return nil, Internal(err)
+	}
+
+	workflowLog := models.WorkflowLog{
+		DrawingID: drawing.ID,
+		ActorID:   userID,
+		Action:    string(action),
+		FromStage: drawing.CurrentStage,
+		ToStage:   nextStage,
+	}
+	if err := txRepo.CreateWorkflowLog(workflowLog); err != nil {
+		return nil, Internal(err)
+	}
+
+	return &workflowOutcome{drawing: drawing, workflowLog: workflowLog, action: action}, nil
+}
+
+// ensureWritable refuses changes to drawings of archived projects. The project is read
+// inside the caller's transaction and stays locked against archiving until it ends.
+func ensureWritable(txRepo repositories.DrawingRepository, projectID uint) error {
+	project, err := txRepo.GetProjectForShare(projectID)
+	if err != nil {
+		return notFoundOr(err, ErrProjectNotFound)
+	}
+	if project.ArchivedAt != nil {
+		return ErrProjectArchived
+	}
+	return nil
+}
+
+// batchItemError prefixes a domain error with the drawing it happened on
+func batchItemError(drawingID uint, err error) error {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.WithMessage("Drawing %d: %s", drawingID, domainErr.Message)
+	}
+	return Internal(err)
+}
+
+// publish sends audit logs and realtime events for committed transitions.
+func (s *Drawing) publish(outcomes []*workflowOutcome) {
+	publishOutcomes(s.auditor, s.broadcaster, outcomes)
+}
+
+func publishOutcomes(auditor Auditor, broadcaster Broadcaster, outcomes []*workflowOutcome) {
+	if len(outcomes) == 0 {
+		return
+	}
 
-	// Post-transaction tasks (Async)
+	// The auditor is asynchronous already; broadcasts hit the database and Redis, so they
+	// run in the background
+	for _, o := range outcomes {
+		auditor.ProduceAuditLog(o.workflowLog)
+	}
 	go func() {
-		s.auditor.ProduceAuditLog(workflowLog)
-		s.broadcaster.BroadcastEvent(drawing.ProjectID, fmt.Sprintf("DRAWING_%s", action), drawing)
+		for _, o := range outcomes {
+			broadcaster.BroadcastEvent(o.drawing.ProjectID, fmt.Sprintf("DRAWING_%s", o.action), o.drawing)
+		}
 	}()
+}
+
+// releaseAssigned unassigns every drawing the user holds (in one project, or all
+// when projectID is 0) without changing its stage, logging each as a release by actorID.
+// Archived projects are read-only, so their drawings keep their assignee.
+func releaseAssigned(txRepo repositories.DrawingRepository, projectID uint, userID uint, actorID uint, comment string) ([]*workflowOutcome, error) {
+	drawings, err := txRepo.GetAssignedForUpdate(projectID, userID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+
+	outcomes := make([]*workflowOutcome, 0, len(drawings))
+	for i := range drawings {
+		drawing := drawings[i]
+		updates := map[string]interface{}{
+			"assignee_id": nil,
+			"version":     drawing.Version + 1,
+		}
+		if err := txRepo.Update(&drawing, updates); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrVersionConflict
+			}
+			return nil, Internal(err)
+		}
 
-	return &drawing, nil
+		workflowLog := models.WorkflowLog{
+			DrawingID: drawing.ID,
+			ActorID:   actorID,
+			Action:    string(models.ActionRelease),
+			FromStage: drawing.CurrentStage,
+			ToStage:   drawing.CurrentStage,
+			Comment:   comment,
+		}
+		if err := txRepo.CreateWorkflowLog(workflowLog); err != nil {
+			return nil, Internal(err)
+		}
+		outcomes = append(outcomes, &workflowOutcome{drawing: drawing, workflowLog: workflowLog, action: models.ActionRelease})
+	}
+	return outcomes, nil
 }
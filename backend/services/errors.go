@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrorKind classifies a domain error so transports can pick a status code
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	KindInvalid
+	KindNotFound
+	KindForbidden
+	KindConflict
+	KindUnprocessable
+	KindUnauthenticated
+	KindTooManyRequests
+)
+
+// Error is a domain error with a stable, machine-readable code.
+// Message is always safe to show to clients; the wrapped Err never is.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches on Code, so errors built with WithMessage still match their sentinel
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns a copy of the error with a more specific client-facing message
+func (e *Error) WithMessage(format string, args ...interface{}) *Error {
+	return &Error{Kind: e.Kind, Code: e.Code, Message: fmt.Sprintf(format, args...), Err: e.Err}
+}
+
+// Extensions exposes the code to GraphQL clients
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+var (
+	ErrDrawingNotFound   = &Error{Kind: KindNotFound, Code: "DRAWING_NOT_FOUND", Message: "Drawing not found"}
+	ErrNotAssignee       = &Error{Kind: KindForbidden, Code: "DRAWING_NOT_ASSIGNED", Message: "Drawing is not assigned to you"}
+	ErrRoleNotAllowed    = &Error{Kind: KindForbidden, Code: "ROLE_NOT_ALLOWED", Message: "Your role is not allowed to perform this action", Err: models.ErrUnauthorizedRole}
+	ErrDrawingTitleTaken = &Error{Kind: KindConflict, Code: "DRAWING_TITLE_TAKEN", Message: "A drawing with this title already exists in this project"}
+	ErrAlreadyClaimed    = &Error{Kind: KindConflict, Code: "DRAWING_ALREADY_CLAIMED", Message: "Drawing already claimed"}
+	ErrVersionConflict   = &Error{Kind: KindConflict, Code: "VERSION_CONFLICT", Message: "Drawing was modified by someone else, reload and try again"}
+	ErrInvalidTransition = &Error{Kind: KindUnprocessable, Code: "INVALID_TRANSITION", Message: "This action is not valid for the drawing's current stage", Err: models.ErrInvalidTransition}
+)
+
+// Internal wraps an unexpected error (usually from the database) without exposing it
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "INTERNAL_ERROR", Message: "Internal server error", Err: err}
+}
+
+// domainError passes domain errors through and hides everything else behind Internal
+func domainError(err error) error {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+	return Internal(err)
+}
+
+// notFoundOr maps gorm's not-found to the given domain error and anything else to Internal.
+// A nil err means the record was found but is not the caller's to see, which is also not-found.
+func notFoundOr(err error, notFound *Error) error {
+	if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound
+	}
+	return Internal(err)
+}
+
+// workflowError maps state machine errors to domain errors
+func workflowError(err error) error {
+	switch {
+	case errors.Is(err, models.ErrUnauthorizedRole):
+		return ErrRoleNotAllowed
+	case errors.Is(err, models.ErrInvalidTransition):
+		return ErrInvalidTransition
+	default:
+		return Internal(err)
+	}
+}
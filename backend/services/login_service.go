@@ -0,0 +1,151 @@
+package services
+
+import (
+	"backend/auth"
+	"backend/models"
+	"backend/repositories"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+)
+
+var (
+	ErrLoginThrottled = &Error{Kind: KindTooManyRequests, Code: "LOGIN_THROTTLED", Message: "Too many failed login attempts, try again later"}
+	ErrAccountLocked  = &Error{Kind: KindTooManyRequests, Code: "ACCOUNT_LOCKED", Message: "Too many failed login attempts, the account is locked for now"}
+)
+
+// Throttle is implemented by auth.LoginThrottle
+type Throttle interface {
+	Begin(account string, ip string) (*auth.LoginAttempt, error)
+	Succeeded(attempt *auth.LoginAttempt) error
+	Unlock(account string) error
+}
+
+// Logins checks passwords behind per-account and per-IP backoff. Unknown usernames are
+// counted and locked like real ones, so throttling does not reveal which accounts exist.
+type Logins struct {
+	authenticator Authenticator
+	throttle      Throttle
+	mfa           *MFA
+	users         repositories.UserRepository
+	auditor       Auditor
+}
+
+func NewLogins(authenticator Authenticator, throttle Throttle, mfa *MFA, users repositories.UserRepository, auditor Auditor) *Logins {
+	return &Logins{
+		authenticator: authenticator,
+		throttle:      throttle,
+		mfa:           mfa,
+		users:         users,
+		auditor:       auditor,
+	}
+}
+
+// Login authenticates a user. Blocked attempts get ErrLoginThrottled or ErrAccountLocked
+// and a retry delay in seconds.
+func (s *Logins) Login(organizationID uint, username string, password string, ip string) (*models.User, int, error) {
+	attempt, retryAfter, err := s.begin(organizationID, username, ip)
+	if err != nil {
+		return nil, retryAfter, err
+	}
+
+	user, err := s.authenticator.Authenticate(organizationID, username, password)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			s.failed(organizationID, username, attempt)
+		}
+		return nil, 0, err
+	}
+
+	if err := s.throttle.Succeeded(attempt); err != nil {
+		log.Printf("Failed to clear login failures for user %d: %v", user.ID, err)
+	}
+	return user, 0, nil
+}
+
+// SecondFactor completes a login with a code from the user's authenticator app or a
+// recovery code. Wrong codes count as failed logins of the account.
+func (s *Logins) SecondFactor(challenge string, code string, ip string) (*models.User, []string, int, error) {
+	user, err := s.mfa.ChallengeUser(challenge)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	attempt, retryAfter, err := s.begin(user.OrganizationID, user.Username, ip)
+	if err != nil {
+		return nil, nil, retryAfter, err
+	}
+
+	recoveryCodes, err := s.mfa.Complete(challenge, user, code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.failed(user.OrganizationID, user.Username, attempt)
+		}
+		return nil, nil, 0, err
+	}
+
+	if err := s.throttle.Succeeded(attempt); err != nil {
+		log.Printf("Failed to clear login failures for user %d: %v", user.ID, err)
+	}
+	return user, recoveryCodes, 0, nil
+}
+
+// Unlock lifts a lockout on a user of the admin's organization
+func (s *Logins) Unlock(userID uint, organizationID uint, actorID uint) error {
+	user, err := s.users.Get(userID)
+	if err != nil || user.OrganizationID != organizationID {
+		return notFoundOr(err, ErrUserNotFound)
+	}
+	if err := s.throttle.Unlock(throttleAccount(organizationID, user.Username)); err != nil {
+		return Internal(err)
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "auth.account_unlocked",
+		ActorID:      actorID,
+		TargetUserID: user.ID,
+	})
+	return nil
+}
+
+// begin reserves an attempt, mapping blocks to domain errors with a retry delay in seconds
+func (s *Logins) begin(organizationID uint, username string, ip string) (*auth.LoginAttempt, int, error) {
+	attempt, err := s.throttle.Begin(throttleAccount(organizationID, username), ip)
+	if err != nil {
+		var throttled *auth.ThrottledError
+		if !errors.As(err, &throttled) {
+			return nil, 0, Internal(err)
+		}
+		retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+		if throttled.Locked && !throttled.ByIP {
+			return nil, retryAfter, ErrAccountLocked
+		}
+		return nil, retryAfter, ErrLoginThrottled
+	}
+	return attempt, 0, nil
+}
+
+// failed audits the lockouts a failed attempt caused
+func (s *Logins) failed(organizationID uint, username string, attempt *auth.LoginAttempt) {
+	if attempt.AccountLocked {
+		event := models.AuditEvent{
+			Type:    "auth.account_locked",
+			Details: map[string]interface{}{"username": username, "ip": attempt.IP, "failures": attempt.AccountFailures},
+		}
+		if user, err := s.users.GetByUsername(organizationID, username); err == nil {
+			event.TargetUserID = user.ID
+		}
+		s.auditor.ProduceAuditEvent(event)
+	}
+	if attempt.IPLocked {
+		s.auditor.ProduceAuditEvent(models.AuditEvent{
+			Type:    "auth.ip_locked",
+			Details: map[string]interface{}{"ip": attempt.IP, "failures": attempt.IPFailures},
+		})
+	}
+}
+
+func throttleAccount(organizationID uint, username string) string {
+	return fmt.Sprintf("%d:%s", organizationID, strings.ToLower(username))
+}
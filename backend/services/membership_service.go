@@ -0,0 +1,240 @@
+package services
+
+import (
+	"backend/models"
+	"backend/repositories"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrNotProjectManager  = &Error{Kind: KindForbidden, Code: "NOT_PROJECT_MANAGER", Message: "Only admins and project owners can manage members"}
+	ErrUserNotFound       = &Error{Kind: KindNotFound, Code: "USER_NOT_FOUND", Message: "User not found"}
+	ErrMemberNotFound     = &Error{Kind: KindNotFound, Code: "MEMBER_NOT_FOUND", Message: "User is not a member of this project"}
+	ErrAlreadyMember      = &Error{Kind: KindConflict, Code: "ALREADY_MEMBER", Message: "User is already a member of this project"}
+	ErrLastOwner          = &Error{Kind: KindConflict, Code: "LAST_OWNER", Message: "A project must keep at least one owner"}
+	ErrGrantRequiresAdmin = &Error{Kind: KindForbidden, Code: "GRANT_REQUIRES_ADMIN", Message: "Only admins can grant the admin project role or change project ownership"}
+)
+
+// MemberChanges holds the fields of a partial membership update; nil means unchanged
+type MemberChanges struct {
+	Role    *models.UserRole
+	IsOwner *bool
+}
+
+// Membership manages who belongs to a project and with which role
+type Membership struct {
+	repo        repositories.ProjectRepository
+	users       repositories.UserRepository
+	auditor     Auditor
+	broadcaster Broadcaster
+}
+
+func NewMembership(repo repositories.ProjectRepository, users repositories.UserRepository, auditor Auditor, broadcaster Broadcaster) *Membership {
+	return &Membership{
+		repo:        repo,
+		users:       users,
+		auditor:     auditor,
+		broadcaster: broadcaster,
+	}
+}
+
+// CanManage reports whether the actor is an admin of the project's organization or an owner of the project
+func (s *Membership) CanManage(projectID uint, actorID uint, actorRole string) error {
+	project, err := s.repo.Get(projectID)
+	if err != nil {
+		return notFoundOr(err, ErrProjectNotFound)
+	}
+	if actorRole == string(models.RoleAdmin) {
+		actor, err := s.users.Get(actorID)
+		if err != nil {
+			return notFoundOr(err, ErrUserNotFound)
+		}
+		if actor.OrganizationID != project.OrganizationID {
+			return ErrProjectNotFound
+		}
+		return nil
+	}
+	member, err := s.repo.GetMember(projectID, actorID)
+	if err != nil {
+		return notFoundOr(err, ErrNotProjectManager)
+	}
+	if !member.IsOwner {
+		return ErrNotProjectManager
+	}
+	return nil
+}
+
+func (s *Membership) List(projectID uint, actorID uint, actorRole string) ([]models.ProjectMember, error) {
+	if err := s.CanManage(projectID, actorID, actorRole); err != nil {
+		return nil, err
+	}
+	members, err := s.repo.GetMembers(projectID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	return members, nil
+}
+
+func (s *Membership) Add(member *models.ProjectMember, actorID uint, actorRole string) error {
+	if err := s.CanManage(member.ProjectID, actorID, actorRole); err != nil {
+		return err
+	}
+	if err := checkGrant(actorRole, member.Role == models.RoleAdmin, member.IsOwner); err != nil {
+		return err
+	}
+	user, err := s.users.Get(member.UserID)
+	if err != nil {
+		return notFoundOr(err, ErrUserNotFound)
+	}
+	project, err := s.repo.Get(member.ProjectID)
+	if err != nil {
+		return notFoundOr(err, ErrProjectNotFound)
+	}
+	// Users of other organizations are invisible here
+	if user.OrganizationID != project.OrganizationID {
+		return ErrUserNotFound
+	}
+
+	if err := s.repo.AddMember(member); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrAlreadyMember
+		}
+		return Internal(err)
+	}
+
+	s.publish("MEMBER_ADDED", "project.member_added", member, actorID, map[string]interface{}{
+		"role":     member.Role,
+		"is_owner": member.IsOwner,
+	})
+	return nil
+}
+
+func (s *Membership) Update(projectID uint, userID uint, changes MemberChanges, actorID uint, actorRole string) (*models.ProjectMember, error) {
+	if err := s.CanManage(projectID, actorID, actorRole); err != nil {
+		return nil, err
+	}
+
+	var member *models.ProjectMember
+	details := map[string]interface{}{}
+	err := s.repo.RunTransaction(func(txRepo repositories.ProjectRepository) error {
+		var err error
+		member, err = txRepo.GetMember(projectID, userID)
+		if err != nil {
+			return notFoundOr(err, ErrMemberNotFound)
+		}
+
+		roleChanged := changes.Role != nil && *changes.Role != member.Role
+		ownerChanged := changes.IsOwner != nil && *changes.IsOwner != member.IsOwner
+		if err := checkGrant(actorRole, roleChanged && *changes.Role == models.RoleAdmin, ownerChanged); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if roleChanged {
+			details["old_role"] = member.Role
+			details["role"] = *changes.Role
+			updates["role"] = *changes.Role
+		}
+		if ownerChanged {
+			if !*changes.IsOwner {
+				if err := ensureAnotherOwner(txRepo, projectID); err != nil {
+					return err
+				}
+			}
+			details["is_owner"] = *changes.IsOwner
+			updates["is_owner"] = *changes.IsOwner
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := txRepo.UpdateMember(member, updates); err != nil {
+			return Internal(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, domainError(err)
+	}
+
+	if len(details) > 0 {
+		s.publish("MEMBER_UPDATED", "project.member_updated", member, actorID, details)
+	}
+	return member, nil
+}
+
+// Remove deletes the membership and releases every drawing the user holds in the project
+func (s *Membership) Remove(projectID uint, userID uint, actorID uint, actorRole string) error {
+	if err := s.CanManage(projectID, actorID, actorRole); err != nil {
+		return err
+	}
+
+	var member *models.ProjectMember
+	var released []*workflowOutcome
+	err := s.repo.RunTransaction(func(txRepo repositories.ProjectRepository) error {
+		var err error
+		member, err = txRepo.GetMember(projectID, userID)
+		if err != nil {
+			return notFoundOr(err, ErrMemberNotFound)
+		}
+		if member.IsOwner {
+			if err := checkGrant(actorRole, false, true); err != nil {
+				return err
+			}
+			if err := ensureAnotherOwner(txRepo, projectID); err != nil {
+				return err
+			}
+		}
+
+		released, err = releaseAssigned(txRepo.Drawings(), projectID, userID, actorID, "Released: assignee removed from project")
+		if err != nil {
+			return err
+		}
+		if err := txRepo.RemoveMember(member); err != nil {
+			return Internal(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return domainError(err)
+	}
+
+	publishOutcomes(s.auditor, s.broadcaster, released)
+	s.publish("MEMBER_REMOVED", "project.member_removed", member, actorID, map[string]interface{}{
+		"released_drawings": len(released),
+	})
+	return nil
+}
+
+// checkGrant keeps project owners from handing out the admin project role, which bypasses
+// the assignee checks of the workflow, and ownership; only admins may do either
+func checkGrant(actorRole string, grantsAdmin bool, changesOwnership bool) error {
+	if (grantsAdmin || changesOwnership) && actorRole != string(models.RoleAdmin) {
+		return ErrGrantRequiresAdmin
+	}
+	return nil
+}
+
+func ensureAnotherOwner(txRepo repositories.ProjectRepository, projectID uint) error {
+	owners, err := txRepo.CountOwnersForUpdate(projectID)
+	if err != nil {
+		return Internal(err)
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func (s *Membership) publish(eventType string, auditType string, member *models.ProjectMember, actorID uint, details map[string]interface{}) {
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         auditType,
+		ActorID:      actorID,
+		ProjectID:    member.ProjectID,
+		TargetUserID: member.UserID,
+		Details:      details,
+	})
+	go s.broadcaster.BroadcastEvent(member.ProjectID, eventType, member)
+}
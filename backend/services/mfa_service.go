@@ -0,0 +1,526 @@
+package services
+
+import (
+	"backend/auth"
+	"backend/models"
+	"backend/repositories"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidMFACode      = &Error{Kind: KindUnauthenticated, Code: "INVALID_MFA_CODE", Message: "Invalid authentication code"}
+	ErrInvalidMFAChallenge = &Error{Kind: KindUnauthenticated, Code: "INVALID_MFA_CHALLENGE", Message: "Login expired or had too many wrong codes, sign in again"}
+	ErrMFAAlreadyEnabled   = &Error{Kind: KindConflict, Code: "MFA_ALREADY_ENABLED", Message: "Two-factor authentication is already enabled"}
+	ErrMFANotEnabled       = &Error{Kind: KindInvalid, Code: "MFA_NOT_ENABLED", Message: "Two-factor authentication is not enabled"}
+	ErrMFANotEnrolling     = &Error{Kind: KindInvalid, Code: "MFA_NOT_ENROLLING", Message: "Start setting up an authenticator app first"}
+	ErrMFARequired         = &Error{Kind: KindForbidden, Code: "MFA_REQUIRED", Message: "Your role requires two-factor authentication"}
+	ErrMFANotApplicable    = &Error{Kind: KindInvalid, Code: "MFA_NOT_APPLICABLE", Message: "This account signs in through single sign-on or API keys; its second factor is managed there"}
+)
+
+const (
+	totpPeriod        = 30
+	recoveryCodeCount = 10
+	// Recovery codes are 10 characters without look-alikes, shown as XXXXX-XXXXX
+	recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	// insecureMFAKey is the MFA_ENCRYPTION_KEY default, which is public
+	insecureMFAKey = "super-secret-mfa-key"
+)
+
+// MFAChallengeStore is implemented by auth.MFAChallenges
+type MFAChallengeStore interface {
+	Create(userID uint) (string, error)
+	Get(token string) (uint, error)
+	Fail(token string) error
+	Consume(token string) error
+	TTL() time.Duration
+}
+
+// MFAChallenge asks the client for a second factor before tokens are issued
+type MFAChallenge struct {
+	Token              string
+	ExpiresIn          time.Duration
+	EnrollmentRequired bool // The user's role requires MFA and they have to set up an authenticator app first
+}
+
+// TOTPEnrollment is shown once, for the user to add to their authenticator app
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	QRCode          string `json:"qr_code"` // PNG data URL of the provisioning URI
+}
+
+type MFAStatus struct {
+	Enabled           bool  `json:"enabled"`
+	Required          bool  `json:"required"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+// MFA manages TOTP authenticators and recovery codes, and the second login step.
+// Roles in requiredRoles cannot log in with a password alone.
+type MFA struct {
+	repo          repositories.MFARepository
+	challenges    MFAChallengeStore
+	tokens        *Tokens
+	auditor       Auditor
+	secrets       cipher.AEAD
+	issuer        string
+	requiredRoles map[models.UserRole]bool
+}
+
+// NewMFA encrypts TOTP secrets with AES-GCM, keyed by the SHA-256 of encryptionKey
+func NewMFA(repo repositories.MFARepository, challenges MFAChallengeStore, tokens *Tokens, auditor Auditor, issuer string, encryptionKey string, requiredRoles []models.UserRole) (*MFA, error) {
+	if encryptionKey == "" {
+		return nil, errors.New("MFA encryption key is empty")
+	}
+	if encryptionKey == insecureMFAKey {
+		log.Println("WARNING: encrypting TOTP secrets with the default MFA key; set MFA_ENCRYPTION_KEY")
+	}
+	key := sha256.Sum256([]byte(encryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	required := make(map[models.UserRole]bool)
+	for _, role := range requiredRoles {
+		required[role] = true
+	}
+	return &MFA{
+		repo:          repo,
+		challenges:    challenges,
+		tokens:        tokens,
+		auditor:       auditor,
+		secrets:       secrets,
+		issuer:        issuer,
+		requiredRoles: required,
+	}, nil
+}
+
+// Begin decides whether a password login needs a second factor. It returns nil when
+// tokens can be issued right away.
+func (s *MFA) Begin(user *models.User) (*MFAChallenge, error) {
+	if err := loginAllowed(user); err != nil {
+		return nil, err
+	}
+	enabled, err := s.enabled(user.ID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	if !enabled && !s.requiredRoles[user.Role] {
+		return nil, nil
+	}
+
+	token, err := s.challenges.Create(user.ID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	return &MFAChallenge{Token: token, ExpiresIn: s.challenges.TTL(), EnrollmentRequired: !enabled}, nil
+}
+
+// ChallengeUser returns the user waiting on a challenge
+func (s *MFA) ChallengeUser(token string) (*models.User, error) {
+	userID, err := s.challenges.Get(token)
+	if err != nil {
+		return nil, challengeError(err)
+	}
+	user, err := s.repo.Users().Get(userID)
+	if err != nil {
+		return nil, notFoundOr(err, ErrInvalidMFAChallenge)
+	}
+	return user, nil
+}
+
+// EnrollForChallenge sets up an authenticator during a login that requires one
+func (s *MFA) EnrollForChallenge(token string) (*TOTPEnrollment, error) {
+	user, err := s.ChallengeUser(token)
+	if err != nil {
+		return nil, err
+	}
+	return s.startEnrollment(user)
+}
+
+// Complete checks the second factor of a login and ends the challenge. During an enrolment
+// at login, the code confirms the new authenticator and the recovery codes are returned.
+func (s *MFA) Complete(token string, user *models.User, code string) ([]string, error) {
+	credential, err := s.repo.GetCredential(user.ID)
+	if err != nil {
+		return nil, notFoundOr(err, ErrMFANotEnrolling)
+	}
+
+	var recoveryCodes []string
+	if credential.ConfirmedAt == nil {
+		recoveryCodes, err = s.confirm(user, code)
+	} else {
+		err = s.verify(user, code, true)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := s.challenges.Fail(token); err != nil {
+				log.Printf("Failed to count MFA failure for user %d: %v", user.ID, err)
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.challenges.Consume(token); err != nil {
+		return nil, challengeError(err)
+	}
+	return recoveryCodes, nil
+}
+
+func (s *MFA) Status(userID uint) (*MFAStatus, error) {
+	user, err := s.repo.Users().Get(userID)
+	if err != nil {
+		return nil, notFoundOr(err, ErrUserNotFound)
+	}
+	enabled, err := s.enabled(userID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	status := &MFAStatus{Enabled: enabled, Required: s.requiredRoles[user.Role]}
+	if enabled {
+		if status.RecoveryCodesLeft, err = s.repo.CountUnusedRecoveryCodes(userID); err != nil {
+			return nil, Internal(err)
+		}
+	}
+	return status, nil
+}
+
+// StartEnrollment creates a new, unconfirmed authenticator secret for the user
+func (s *MFA) StartEnrollment(userID uint) (*TOTPEnrollment, error) {
+	user, err := s.repo.Users().Get(userID)
+	if err != nil {
+		return nil, notFoundOr(err, ErrUserNotFound)
+	}
+	return s.startEnrollment(user)
+}
+
+// ConfirmEnrollment turns MFA on with a first code from the app and returns the recovery codes
+func (s *MFA) ConfirmEnrollment(userID uint, code string) ([]string, error) {
+	user, err := s.repo.Users().Get(userID)
+	if err != nil {
+		return nil, notFoundOr(err, ErrUserNotFound)
+	}
+	return s.confirm(user, code)
+}
+
+// Disable turns MFA off for a user whose role does not require it
+func (s *MFA) Disable(userID uint, code string) error {
+	user, err := s.repo.Users().Get(userID)
+	if err != nil {
+		return notFoundOr(err, ErrUserNotFound)
+	}
+	if s.requiredRoles[user.Role] {
+		return ErrMFARequired
+	}
+	if err := s.verify(user, code, true); err != nil {
+		return err
+	}
+	if err := s.remove(user.ID); err != nil {
+		return err
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "mfa.disabled",
+		ActorID:      user.ID,
+		TargetUserID: user.ID,
+	})
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes; it needs a code from the app
+func (s *MFA) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.repo.Users().Get(userID)
+	if err != nil {
+		return nil, notFoundOr(err, ErrUserNotFound)
+	}
+	if err := s.verify(user, code, false); err != nil {
+		return nil, err
+	}
+	codes, hashed, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	if err := s.repo.ReplaceRecoveryCodes(user.ID, hashed); err != nil {
+		return nil, Internal(err)
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "mfa.recovery_codes_regenerated",
+		ActorID:      user.ID,
+		TargetUserID: user.ID,
+	})
+	return codes, nil
+}
+
+// Reset removes the MFA of a user of the admin's organization, e.g. after a lost phone,
+// and signs them out. If their role requires MFA, they set it up again at the next login.
+func (s *MFA) Reset(userID uint, organizationID uint, actorID uint) error {
+	user, err := s.repo.Users().Get(userID)
+	if err != nil || user.OrganizationID != organizationID {
+		return notFoundOr(err, ErrUserNotFound)
+	}
+	if err := s.remove(user.ID); err != nil {
+		return err
+	}
+	if err := s.tokens.RevokeAll(user.ID, actorID); err != nil {
+		return err
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "mfa.reset",
+		ActorID:      actorID,
+		TargetUserID: user.ID,
+	})
+	return nil
+}
+
+func (s *MFA) enabled(userID uint) (bool, error) {
+	credential, err := s.repo.GetCredential(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return credential.ConfirmedAt != nil, nil
+}
+
+func (s *MFA) startEnrollment(user *models.User) (*TOTPEnrollment, error) {
+	if user.ServiceAccount || (user.ExternalSubject != nil && user.ExternalIssuer != auth.LDAPIssuer) {
+		return nil, ErrMFANotApplicable
+	}
+	enabled, err := s.enabled(user.ID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.issuer,
+		AccountName: user.Username,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, Internal(err)
+	}
+	sealed, err := s.seal(key.Secret())
+	if err != nil {
+		return nil, Internal(err)
+	}
+	if err := s.repo.SaveCredential(&models.TOTPCredential{UserID: user.ID, Secret: sealed}); err != nil {
+		return nil, Internal(err)
+	}
+
+	image, err := key.Image(256, 256)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	var qr bytes.Buffer
+	if err := png.Encode(&qr, image); err != nil {
+		return nil, Internal(err)
+	}
+	return &TOTPEnrollment{
+		Secret:          key.Secret(),
+		ProvisioningURI: key.URL(),
+		QRCode:          "data:image/png;base64," + base64.StdEncoding.EncodeToString(qr.Bytes()),
+	}, nil
+}
+
+// confirm checks the first code of a new authenticator, enables it and issues recovery codes
+func (s *MFA) confirm(user *models.User, code string) ([]string, error) {
+	codes, hashed, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+
+	err = s.repo.RunTransaction(func(txRepo repositories.MFARepository) error {
+		credential, err := txRepo.GetCredentialForUpdate(user.ID)
+		if err != nil {
+			return notFoundOr(err, ErrMFANotEnrolling)
+		}
+		if credential.ConfirmedAt != nil {
+			return ErrMFAAlreadyEnabled
+		}
+		step, ok := s.matchStep(credential, normalizeCode(code))
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		if err := txRepo.UpdateCredential(credential, map[string]interface{}{"confirmed_at": time.Now(), "last_used_step": step}); err != nil {
+			return err
+		}
+		return txRepo.ReplaceRecoveryCodes(user.ID, hashed)
+	})
+	if err != nil {
+		return nil, domainError(err)
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "mfa.enabled",
+		ActorID:      user.ID,
+		TargetUserID: user.ID,
+	})
+	return codes, nil
+}
+
+// verify accepts a current code from the app and, if allowed, an unused recovery code
+func (s *MFA) verify(user *models.User, code string, allowRecovery bool) error {
+	code = normalizeCode(code)
+	if len(code) == int(otp.DigitsSix) {
+		err := s.repo.RunTransaction(func(txRepo repositories.MFARepository) error {
+			credential, err := txRepo.GetCredentialForUpdate(user.ID)
+			if err != nil {
+				return notFoundOr(err, ErrMFANotEnabled)
+			}
+			if credential.ConfirmedAt == nil {
+				return ErrMFANotEnabled
+			}
+			step, ok := s.matchStep(credential, code)
+			if !ok {
+				return ErrInvalidMFACode
+			}
+			return txRepo.UpdateCredential(credential, map[string]interface{}{"last_used_step": step})
+		})
+		if err != nil {
+			return domainError(err)
+		}
+		return nil
+	}
+
+	if !allowRecovery {
+		return ErrInvalidMFACode
+	}
+	used, err := s.repo.UseRecoveryCode(user.ID, hashToken(code), time.Now())
+	if err != nil {
+		return Internal(err)
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+
+	left, _ := s.repo.CountUnusedRecoveryCodes(user.ID)
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "mfa.recovery_code_used",
+		ActorID:      user.ID,
+		TargetUserID: user.ID,
+		Details:      map[string]interface{}{"codes_left": left},
+	})
+	return nil
+}
+
+// matchStep finds the time step a code belongs to, allowing one step of clock drift either
+// way. Steps up to the last accepted one are refused, so each code works only once.
+func (s *MFA) matchStep(credential *models.TOTPCredential, code string) (int64, bool) {
+	secret, err := s.open(credential.Secret)
+	if err != nil {
+		log.Printf("Failed to decrypt TOTP secret of user %d: %v", credential.UserID, err)
+		return 0, false
+	}
+
+	current := time.Now().Unix() / totpPeriod
+	for step := current - 1; step <= current+1; step++ {
+		if step <= credential.LastUsedStep {
+			continue
+		}
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func (s *MFA) remove(userID uint) error {
+	err := s.repo.RunTransaction(func(txRepo repositories.MFARepository) error {
+		if err := txRepo.DeleteCredential(userID); err != nil {
+			return err
+		}
+		return txRepo.ReplaceRecoveryCodes(userID, nil)
+	})
+	if err != nil {
+		return Internal(err)
+	}
+	return nil
+}
+
+func (s *MFA) seal(secret string) (string, error) {
+	nonce := make([]byte, s.secrets.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(s.secrets.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+func (s *MFA) open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < s.secrets.NonceSize() {
+		return "", errors.New("sealed secret too short")
+	}
+	nonce, ciphertext := data[:s.secrets.NonceSize()], data[s.secrets.NonceSize():]
+	secret, err := s.secrets.Open(nil, nonce, ciphertext, nil)
+	return string(secret), err
+}
+
+// newRecoveryCodes returns the codes to show once and the hashed rows to store
+func newRecoveryCodes(userID uint) ([]string, []models.RecoveryCode, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashed := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		for j := range b {
+			b[j] = recoveryCodeAlphabet[int(b[j])%len(recoveryCodeAlphabet)]
+		}
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+		hashed[i] = models.RecoveryCode{UserID: userID, CodeHash: hashToken(string(b))}
+	}
+	return codes, hashed, nil
+}
+
+// normalizeCode accepts codes typed with spaces, dashes or lower case
+func normalizeCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+func challengeError(err error) error {
+	if errors.Is(err, auth.ErrMFAChallenge) {
+		return ErrInvalidMFAChallenge
+	}
+	return Internal(err)
+}
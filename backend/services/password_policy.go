@@ -0,0 +1,184 @@
+package services
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// bcrypt ignores everything after 72 bytes
+const maxPasswordBytes = 72
+
+var (
+	ErrPasswordTooShort = &Error{Kind: KindInvalid, Code: "PASSWORD_TOO_SHORT", Message: "Password is too short"}
+	ErrPasswordTooLong  = &Error{Kind: KindInvalid, Code: "PASSWORD_TOO_LONG", Message: "Password must be at most 72 bytes"}
+	ErrPasswordBreached = &Error{Kind: KindInvalid, Code: "PASSWORD_BREACHED", Message: "This password appears in a list of breached passwords, choose another"}
+	ErrPasswordReused   = &Error{Kind: KindInvalid, Code: "PASSWORD_REUSED", Message: "Choose a password you have not used recently"}
+)
+
+// PasswordPolicy decides which new passwords are acceptable.
+// HistorySize is how many recent passwords, the current one included, may not be reused.
+type PasswordPolicy struct {
+	MinLength   int
+	HistorySize int
+	breached    *breachedList
+}
+
+// LoadPasswordPolicy opens the breached password list, one entry per line. Entries are
+// either plain passwords (compared case-insensitively) or SHA-1 hex digests, optionally
+// followed by ":count" as in the Have I Been Pwned downloads. The list is searched on
+// disk rather than loaded, so it must be sorted in byte order once lowercased.
+func LoadPasswordPolicy(minLength int, historySize int, breachedFile string) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{MinLength: minLength, HistorySize: historySize}
+	if breachedFile == "" {
+		return policy, nil
+	}
+
+	list, err := openBreachedList(breachedFile)
+	if err != nil {
+		return nil, err
+	}
+	policy.breached = list
+	return policy, nil
+}
+
+// Check applies the length and breached-list rules; reuse is checked by Passwords
+func (p *PasswordPolicy) Check(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return ErrPasswordTooShort.WithMessage("Password must be at least %d characters", p.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return ErrPasswordTooLong
+	}
+	if p.breached == nil {
+		return nil
+	}
+	digest := sha1.Sum([]byte(password))
+	for _, key := range []string{strings.ToLower(password), hex.EncodeToString(digest[:])} {
+		found, err := p.breached.contains(key)
+		if err != nil {
+			return Internal(err)
+		}
+		if found {
+			return ErrPasswordBreached
+		}
+	}
+	return nil
+}
+
+// breachedList binary searches a sorted list file, so lists of any size use no memory
+type breachedList struct {
+	file *os.File
+	size int64
+}
+
+// sortCheckLines is how many leading lines are checked for order when the list is opened
+const sortCheckLines = 1000
+
+func openBreachedList(path string) (*breachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening breached password list: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("reading breached password list: %w", err)
+	}
+
+	list := &breachedList{file: file, size: info.Size()}
+	if err := list.checkSorted(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return list, nil
+}
+
+// checkSorted catches unsorted lists early; lookups in them would miss entries
+func (l *breachedList) checkSorted() error {
+	scanner := bufio.NewScanner(io.NewSectionReader(l.file, 0, l.size))
+	previous := ""
+	for n := 1; n <= sortCheckLines && scanner.Scan(); n++ {
+		key := breachedKey(scanner.Text())
+		if key < previous {
+			return fmt.Errorf("breached password list is not sorted at line %d; sort it with: tr 'A-Z' 'a-z' < list | LC_ALL=C sort", n)
+		}
+		previous = key
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading breached password list: %w", err)
+	}
+	return nil
+}
+
+func (l *breachedList) contains(key string) (bool, error) {
+	// Lines starting before lo sort before key; the first line starting at or after hi does not
+	lo, hi := int64(0), l.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		line, next, err := l.lineFrom(mid)
+		if err != nil {
+			return false, err
+		}
+		if next > mid && breachedKey(line) < key {
+			lo = next
+		} else {
+			hi = mid
+		}
+	}
+	line, next, err := l.lineFrom(lo)
+	if err != nil {
+		return false, err
+	}
+	return next > lo && breachedKey(line) == key, nil
+}
+
+// lineFrom returns the first line starting at or after offset and where the line after it
+// starts. next is not past offset when there is no such line.
+func (l *breachedList) lineFrom(offset int64) (string, int64, error) {
+	start := offset
+	if offset > 0 {
+		start = offset - 1
+	}
+	reader := bufio.NewReader(io.NewSectionReader(l.file, start, l.size-start))
+	pos := start
+	if offset > 0 {
+		skipped, err := reader.ReadString('\n')
+		pos += int64(len(skipped))
+		if err == io.EOF {
+			return "", offset, nil
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("reading breached password list: %w", err)
+		}
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", 0, fmt.Errorf("reading breached password list: %w", err)
+	}
+	if line == "" {
+		return "", offset, nil
+	}
+	return line, pos + int64(len(line)), nil
+}
+
+// breachedKey is what a list line is compared by: its lowercased digest or password
+func breachedKey(line string) string {
+	line = strings.TrimSpace(line)
+	if digest, _, _ := strings.Cut(line, ":"); isSHA1Hex(digest) {
+		return strings.ToLower(digest)
+	}
+	return strings.ToLower(line)
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
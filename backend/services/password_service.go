@@ -0,0 +1,252 @@
+package services
+
+import (
+	"backend/models"
+	"backend/repositories"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrWrongPassword          = &Error{Kind: KindInvalid, Code: "WRONG_PASSWORD", Message: "Current password is incorrect"}
+	ErrInvalidResetToken      = &Error{Kind: KindInvalid, Code: "INVALID_RESET_TOKEN", Message: "Reset link is invalid, expired or already used"}
+	ErrPasswordNotManagedHere = &Error{Kind: KindInvalid, Code: "PASSWORD_MANAGED_EXTERNALLY", Message: "This account signs in through single sign-on or a service key and has no password here"}
+)
+
+// Mailer sends plain-text mail
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
+
+// Passwords changes and resets passwords. Every change revokes the user's sessions.
+type Passwords struct {
+	repo     repositories.PasswordRepository
+	logins   *Logins
+	tokens   *Tokens
+	policy   *PasswordPolicy
+	mailer   Mailer
+	auditor  Auditor
+	resetURL string // The reset token is appended as ?token=
+	resetTTL time.Duration
+}
+
+func NewPasswords(repo repositories.PasswordRepository, logins *Logins, tokens *Tokens, policy *PasswordPolicy, mailer Mailer, auditor Auditor, resetURL string, resetTTL time.Duration) *Passwords {
+	return &Passwords{
+		repo:     repo,
+		logins:   logins,
+		tokens:   tokens,
+		policy:   policy,
+		mailer:   mailer,
+		auditor:  auditor,
+		resetURL: resetURL,
+		resetTTL: resetTTL,
+	}
+}
+
+// Change sets a new password for a user who knows the current one. Wrong current passwords
+// count as failed logins of the account, and blocked attempts get a retry delay in seconds.
+func (s *Passwords) Change(userID uint, current string, next string, ip string) (int, error) {
+	user, err := s.repo.Users().Get(userID)
+	if err != nil {
+		return 0, notFoundOr(err, ErrUserNotFound)
+	}
+	if !hasLocalPassword(user) {
+		return 0, ErrPasswordNotManagedHere
+	}
+	attempt, retryAfter, err := s.logins.begin(user.OrganizationID, user.Username, ip)
+	if err != nil {
+		return retryAfter, err
+	}
+
+	verified := false
+	err = s.repo.RunTransaction(func(txRepo repositories.PasswordRepository) error {
+		user, err := txRepo.Users().Get(userID)
+		if err != nil {
+			return notFoundOr(err, ErrUserNotFound)
+		}
+		if !hasLocalPassword(user) {
+			return ErrPasswordNotManagedHere
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(current)) != nil {
+			return ErrWrongPassword
+		}
+		verified = true
+		return s.set(txRepo, user, next)
+	})
+	if verified {
+		if err := s.logins.throttle.Succeeded(attempt); err != nil {
+			log.Printf("Failed to clear login failures for user %d: %v", userID, err)
+		}
+	} else if errors.Is(err, ErrWrongPassword) {
+		s.logins.failed(user.OrganizationID, user.Username, attempt)
+	}
+	if err != nil {
+		return 0, domainError(err)
+	}
+	return 0, s.changed(userID, userID, "self")
+}
+
+// RequestReset mails a reset link if the user exists and has an email address.
+// It never tells the caller whether that was the case.
+func (s *Passwords) RequestReset(organizationID uint, username string) {
+	user, err := s.repo.Users().GetByUsername(organizationID, username)
+	if err != nil || user.Email == "" || !hasLocalPassword(user) {
+		return
+	}
+	token, err := s.createResetToken(user.ID, nil)
+	if err != nil {
+		log.Printf("Failed to create password reset token for user %d: %v", user.ID, err)
+		return
+	}
+	if err := s.mailer.Send(user.Email, "Reset your password", s.resetMail(token)); err != nil {
+		log.Printf("Failed to send password reset mail to user %d: %v", user.ID, err)
+		return
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "user.password_reset_requested",
+		ActorID:      user.ID,
+		TargetUserID: user.ID,
+	})
+}
+
+// AdminReset starts a reset for a user of the admin's organization. The link is mailed
+// when the user has an email address, and returned so the admin can hand it over otherwise.
+func (s *Passwords) AdminReset(userID uint, organizationID uint, actorID uint) (string, bool, error) {
+	user, err := s.repo.Users().Get(userID)
+	if err != nil || user.OrganizationID != organizationID {
+		return "", false, notFoundOr(err, ErrUserNotFound)
+	}
+	if !hasLocalPassword(user) {
+		return "", false, ErrPasswordNotManagedHere
+	}
+	token, err := s.createResetToken(user.ID, &actorID)
+	if err != nil {
+		return "", false, Internal(err)
+	}
+
+	mailed := false
+	if user.Email != "" {
+		if err := s.mailer.Send(user.Email, "Reset your password", s.resetMail(token)); err != nil {
+			log.Printf("Failed to send password reset mail to user %d: %v", user.ID, err)
+		} else {
+			mailed = true
+		}
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "user.password_reset_issued",
+		ActorID:      actorID,
+		TargetUserID: user.ID,
+		Details:      map[string]interface{}{"mailed": mailed},
+	})
+	return s.resetLink(token), mailed, nil
+}
+
+// Reset sets a new password with a reset token. The token, and any other open one, is used up.
+func (s *Passwords) Reset(token string, next string) error {
+	var userID uint
+	err := s.repo.RunTransaction(func(txRepo repositories.PasswordRepository) error {
+		resetToken, err := txRepo.GetResetTokenForUpdate(hashToken(token))
+		if err != nil {
+			return notFoundOr(err, ErrInvalidResetToken)
+		}
+		if resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+			return ErrInvalidResetToken
+		}
+		user, err := txRepo.Users().Get(resetToken.UserID)
+		if err != nil {
+			return notFoundOr(err, ErrInvalidResetToken)
+		}
+		if err := s.set(txRepo, user, next); err != nil {
+			return err
+		}
+		userID = user.ID
+		return txRepo.UseResetTokens(user.ID, time.Now())
+	})
+	if err != nil {
+		return domainError(err)
+	}
+	return s.changed(userID, userID, "reset")
+}
+
+// set checks the policy and the user's recent passwords, then stores the new hash
+// and keeps the old one in the history
+func (s *Passwords) set(txRepo repositories.PasswordRepository, user *models.User, next string) error {
+	if err := s.policy.Check(next); err != nil {
+		return err
+	}
+
+	if s.policy.HistorySize > 0 {
+		recent := []string{user.PasswordHash}
+		history, err := txRepo.RecentHistory(user.ID, s.policy.HistorySize-1)
+		if err != nil {
+			return err
+		}
+		for _, entry := range history {
+			recent = append(recent, entry.PasswordHash)
+		}
+		for _, hash := range recent {
+			if bcrypt.CompareHashAndPassword([]byte(hash), []byte(next)) == nil {
+				return ErrPasswordReused
+			}
+		}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(next), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := txRepo.AddHistory(&models.PasswordHistory{UserID: user.ID, PasswordHash: user.PasswordHash}); err != nil {
+		return err
+	}
+	return txRepo.Users().Update(user, map[string]interface{}{"password_hash": string(hash)})
+}
+
+// changed signs the user out everywhere after a password change
+func (s *Passwords) changed(userID uint, actorID uint, via string) error {
+	if err := s.tokens.RevokeAll(userID, actorID); err != nil {
+		return err
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "user.password_changed",
+		ActorID:      actorID,
+		TargetUserID: userID,
+		Details:      map[string]interface{}{"via": via},
+	})
+	return nil
+}
+
+func (s *Passwords) createResetToken(userID uint, createdByID *uint) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	err = s.repo.CreateResetToken(&models.PasswordResetToken{
+		UserID:      userID,
+		TokenHash:   hashToken(token),
+		ExpiresAt:   time.Now().Add(s.resetTTL),
+		CreatedByID: createdByID,
+	})
+	return token, err
+}
+
+func (s *Passwords) resetLink(token string) string {
+	return s.resetURL + "?token=" + token
+}
+
+func (s *Passwords) resetMail(token string) string {
+	return fmt.Sprintf("Someone asked to reset the password of your QC System account.\n\n"+
+		"Open this link within %s to choose a new password:\n%s\n\n"+
+		"If this was not you, you can ignore this mail.\n", s.resetTTL, s.resetLink(token))
+}
+
+// hasLocalPassword is false for service accounts and users from SSO or LDAP
+func hasLocalPassword(user *models.User) bool {
+	return !user.ServiceAccount && user.ExternalSubject == nil
+}
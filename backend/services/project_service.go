@@ -0,0 +1,349 @@
+package services
+
+import (
+	"backend/models"
+	"backend/repositories"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrProjectNotFound    = &Error{Kind: KindNotFound, Code: "PROJECT_NOT_FOUND", Message: "Project not found"}
+	ErrProjectNameTaken   = &Error{Kind: KindConflict, Code: "PROJECT_NAME_TAKEN", Message: "A project with this name already exists"}
+	ErrProjectNotArchived = &Error{Kind: KindConflict, Code: "PROJECT_NOT_ARCHIVED", Message: "Project is not archived"}
+)
+
+type Project struct {
+	repo        repositories.ProjectRepository
+	auditor     Auditor
+	broadcaster Broadcaster
+}
+
+func NewProject(repo repositories.ProjectRepository, auditor Auditor, broadcaster Broadcaster) *Project {
+	return &Project{
+		repo:        repo,
+		auditor:     auditor,
+		broadcaster: broadcaster,
+	}
+}
+
+// ProjectChanges holds the fields of a partial update; nil means unchanged
+type ProjectChanges struct {
+	Name        *string
+	Description *string
+	IsTemplate  *bool
+}
+
+// CloneOptions controls what Clone copies besides the project itself
+type CloneOptions struct {
+	Name            string
+	Description     *string
+	IncludeMembers  bool
+	IncludeDrawings bool
+}
+
+func (s *Project) Get(id uint) (*models.Project, error) {
+	project, err := s.repo.Get(id)
+	if err != nil {
+		return nil, notFoundOr(err, ErrProjectNotFound)
+	}
+	return project, nil
+}
+
+// List returns every project of the organization to admins, and to everyone else only
+// the projects they are a member of
+func (s *Project) List(organizationID uint, userID uint, globalRole string, includeArchived bool) ([]models.Project, error) {
+	if globalRole != string(models.RoleAdmin) {
+		return s.memberProjects(userID, includeArchived, false)
+	}
+	projects, err := s.repo.List(organizationID, includeArchived)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	return projects, nil
+}
+
+// ListTemplates returns the live templates visible to the caller, with the same rule as List
+func (s *Project) ListTemplates(organizationID uint, userID uint, globalRole string) ([]models.Project, error) {
+	if globalRole != string(models.RoleAdmin) {
+		return s.memberProjects(userID, false, true)
+	}
+	projects, err := s.repo.ListTemplates(organizationID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	return projects, nil
+}
+
+func (s *Project) memberProjects(userID uint, includeArchived bool, templatesOnly bool) ([]models.Project, error) {
+	memberships, err := s.repo.ListForUser(userID, includeArchived)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	projects := make([]models.Project, 0, len(memberships))
+	for _, membership := range memberships {
+		if !templatesOnly || membership.IsTemplate {
+			projects = append(projects, membership.Project)
+		}
+	}
+	return projects, nil
+}
+
+// ListForUser returns the projects the user is a member of, with their role in each
+func (s *Project) ListForUser(userID uint, includeArchived bool) ([]repositories.MemberProject, error) {
+	projects, err := s.repo.ListForUser(userID, includeArchived)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	return projects, nil
+}
+
+// Create stores the project and makes its creator an owning member with their global role
+func (s *Project) Create(project *models.Project, creatorID uint, creatorRole models.UserRole) error {
+	err := s.repo.RunTransaction(func(txRepo repositories.ProjectRepository) error {
+		if err := txRepo.Create(project); err != nil {
+			return projectWriteError(err)
+		}
+		return txRepo.AddMember(&models.ProjectMember{
+			ProjectID: project.ID,
+			UserID:    creatorID,
+			Role:      creatorRole,
+			IsOwner:   true,
+		})
+	})
+	if err != nil {
+		return domainError(err)
+	}
+	return nil
+}
+
+func (s *Project) Update(id uint, changes ProjectChanges) (*models.Project, error) {
+	project, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if changes.Name != nil {
+		updates["name"] = *changes.Name
+	}
+	if changes.Description != nil {
+		updates["description"] = *changes.Description
+	}
+	if changes.IsTemplate != nil {
+		updates["is_template"] = *changes.IsTemplate
+	}
+	if len(updates) == 0 {
+		return project, nil
+	}
+
+	if err := s.repo.Update(project, updates); err != nil {
+		return nil, projectWriteError(err)
+	}
+	return project, nil
+}
+
+// Clone creates a new project from an existing one (usually a template) in one transaction.
+// Members keep their roles, ownership and source; drawings are copied back to the unassigned
+// stage with no assignee or workflow history. The creator always ends up as a manual owner.
+// Archived projects cannot be cloned.
+func (s *Project) Clone(sourceID uint, opts CloneOptions, creatorID uint, creatorRole models.UserRole) (*models.Project, error) {
+	var clone *models.Project
+	err := s.repo.RunTransaction(func(txRepo repositories.ProjectRepository) error {
+		source, err := txRepo.Get(sourceID)
+		if err != nil {
+			return notFoundOr(err, ErrProjectNotFound)
+		}
+		if source.ArchivedAt != nil {
+			return ErrProjectArchived
+		}
+
+		clone = &models.Project{
+			OrganizationID: source.OrganizationID,
+			Name:           opts.Name,
+			Description:    source.Description,
+		}
+		if opts.Description != nil {
+			clone.Description = *opts.Description
+		}
+		if err := txRepo.Create(clone); err != nil {
+			return projectWriteError(err)
+		}
+
+		creatorCopied := false
+		if opts.IncludeMembers {
+			members, err := txRepo.GetMembers(source.ID)
+			if err != nil {
+				return err
+			}
+			for _, member := range members {
+				copied := models.ProjectMember{
+					ProjectID: clone.ID,
+					UserID:    member.UserID,
+					Role:      member.Role,
+					IsOwner:   member.IsOwner,
+					Source:    member.Source,
+				}
+				if member.UserID == creatorID {
+					copied.IsOwner = true
+					copied.Source = models.MemberSourceManual
+					creatorCopied = true
+				}
+				if err := txRepo.AddMember(&copied); err != nil {
+					return err
+				}
+			}
+		}
+		if !creatorCopied {
+			err := txRepo.AddMember(&models.ProjectMember{
+				ProjectID: clone.ID,
+				UserID:    creatorID,
+				Role:      creatorRole,
+				IsOwner:   true,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if opts.IncludeDrawings {
+			drawings, err := txRepo.Drawings().GetByProject(source.ID)
+			if err != nil {
+				return err
+			}
+			for _, drawing := range drawings {
+				err := txRepo.Drawings().Create(&models.Drawing{
+					Title:        drawing.Title,
+					Description:  drawing.Description,
+					ProjectID:    clone.ID,
+					AuthorID:     drawing.AuthorID,
+					CurrentStage: models.StageUnassigned,
+					Revision:     1,
+					DrawingURL:   drawing.DrawingURL,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, domainError(err)
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:      "project.cloned",
+		ActorID:   creatorID,
+		ProjectID: clone.ID,
+		Details: map[string]interface{}{
+			"source_project_id": sourceID,
+			"include_members":   opts.IncludeMembers,
+			"include_drawings":  opts.IncludeDrawings,
+		},
+	})
+	return clone, nil
+}
+
+// Stats returns the dashboard figures for a project. Weeks start on Monday.
+func (s *Project) Stats(id uint) (*repositories.ProjectStats, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	weekStart := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	stats, err := s.repo.Drawings().GetProjectStats(id, weekStart, monthStart)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	return stats, nil
+}
+
+func (s *Project) Delete(id uint) error {
+	err := s.repo.RunTransaction(func(txRepo repositories.ProjectRepository) error {
+		project, err := txRepo.Get(id)
+		if err != nil {
+			return notFoundOr(err, ErrProjectNotFound)
+		}
+		return txRepo.Delete(project)
+	})
+	if err != nil {
+		return domainError(err)
+	}
+	return nil
+}
+
+// Archive freezes the project: drawings can no longer be created, edited or moved through the workflow
+func (s *Project) Archive(id uint, reason string, actorID uint) (*models.Project, error) {
+	project, err := s.setArchived(id, true, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishArchiveChange(project, "PROJECT_ARCHIVED", "project.archived", reason, actorID)
+	return project, nil
+}
+
+// Unarchive makes the project writable again. A reason is required for the audit trail.
+func (s *Project) Unarchive(id uint, reason string, actorID uint) (*models.Project, error) {
+	project, err := s.setArchived(id, false, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishArchiveChange(project, "PROJECT_UNARCHIVED", "project.unarchived", reason, actorID)
+	return project, nil
+}
+
+// setArchived checks and changes the archive state under a row lock, so that of two
+// concurrent archives (or unarchives) only one succeeds
+func (s *Project) setArchived(id uint, archived bool, reason string) (*models.Project, error) {
+	var project *models.Project
+	err := s.repo.RunTransaction(func(txRepo repositories.ProjectRepository) error {
+		var err error
+		project, err = txRepo.GetForUpdate(id)
+		if err != nil {
+			return notFoundOr(err, ErrProjectNotFound)
+		}
+		if archived && project.ArchivedAt != nil {
+			return ErrProjectArchived
+		}
+		if !archived && project.ArchivedAt == nil {
+			return ErrProjectNotArchived
+		}
+
+		updates := map[string]interface{}{"archived_at": nil, "archive_reason": ""}
+		if archived {
+			updates = map[string]interface{}{"archived_at": time.Now(), "archive_reason": reason}
+		}
+		return txRepo.Update(project, updates)
+	})
+	if err != nil {
+		return nil, domainError(err)
+	}
+	return project, nil
+}
+
+func (s *Project) publishArchiveChange(project *models.Project, eventType string, auditType string, reason string, actorID uint) {
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:      auditType,
+		ActorID:   actorID,
+		ProjectID: project.ID,
+		Details:   map[string]interface{}{"reason": reason},
+	})
+	go s.broadcaster.BroadcastEvent(project.ID, eventType, project)
+}
+
+func projectWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrProjectNameTaken
+	}
+	return Internal(err)
+}
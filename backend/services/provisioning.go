@@ -0,0 +1,311 @@
+package services
+
+import (
+	"backend/auth"
+	"backend/models"
+	"backend/repositories"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// GroupRole gives members of a group a global role
+type GroupRole struct {
+	Group string
+	Role  models.UserRole
+}
+
+// GroupProject gives members of a group a membership in a project
+type GroupProject struct {
+	Group     string
+	ProjectID uint
+	Role      models.UserRole
+	IsOwner   bool
+}
+
+// GroupMapping turns directory or IdP groups into roles and memberships. Roles are checked in order,
+// so list the most privileged first. Users in no mapped group get DefaultRole, or are
+// turned away when it is empty.
+type GroupMapping struct {
+	Roles       []GroupRole
+	Projects    []GroupProject
+	DefaultRole models.UserRole
+}
+
+// ParseGroupMapping reads the mapping from config. roles is "group=role,...";
+// projects is "group=projectID:role[:owner],...".
+func ParseGroupMapping(roles string, projects string, defaultRole string) (GroupMapping, error) {
+	mapping := GroupMapping{DefaultRole: models.UserRole(defaultRole)}
+	if defaultRole != "" && !validRole(mapping.DefaultRole) {
+		return mapping, fmt.Errorf("unknown default role %q", defaultRole)
+	}
+
+	for _, entry := range splitList(roles) {
+		group, role, ok := strings.Cut(entry, "=")
+		if !ok || group == "" || !validRole(models.UserRole(role)) {
+			return mapping, fmt.Errorf("invalid role mapping %q, expected group=role", entry)
+		}
+		mapping.Roles = append(mapping.Roles, GroupRole{Group: group, Role: models.UserRole(role)})
+	}
+
+	for _, entry := range splitList(projects) {
+		group, spec, ok := strings.Cut(entry, "=")
+		parts := strings.Split(spec, ":")
+		if !ok || group == "" || len(parts) < 2 || len(parts) > 3 {
+			return mapping, fmt.Errorf("invalid project mapping %q, expected group=projectID:role[:owner]", entry)
+		}
+		projectID, err := strconv.ParseUint(parts[0], 10, 32)
+		if err != nil || !validRole(models.UserRole(parts[1])) || (len(parts) == 3 && parts[2] != "owner") {
+			return mapping, fmt.Errorf("invalid project mapping %q, expected group=projectID:role[:owner]", entry)
+		}
+		mapping.Projects = append(mapping.Projects, GroupProject{
+			Group:     group,
+			ProjectID: uint(projectID),
+			Role:      models.UserRole(parts[1]),
+			IsOwner:   len(parts) == 3,
+		})
+	}
+	return mapping, nil
+}
+
+func (m GroupMapping) role(groups []string) (models.UserRole, bool) {
+	for _, mapped := range m.Roles {
+		if slices.Contains(groups, mapped.Group) {
+			return mapped.Role, true
+		}
+	}
+	return m.DefaultRole, m.DefaultRole != ""
+}
+
+// memberships returns one mapped membership per project; like roles, the first matching entry wins
+func (m GroupMapping) memberships(groups []string) []GroupProject {
+	var memberships []GroupProject
+	for _, mapped := range m.Projects {
+		if !slices.Contains(groups, mapped.Group) {
+			continue
+		}
+		if slices.ContainsFunc(memberships, func(g GroupProject) bool { return g.ProjectID == mapped.ProjectID }) {
+			continue
+		}
+		memberships = append(memberships, mapped)
+	}
+	return memberships
+}
+
+// externalUsers keeps users of an external identity source (OIDC or LDAP) in sync with it.
+// Users are created on their first login, and their role and mapped project memberships
+// follow the groups on every login. Memberships granted by hand are left alone.
+type externalUsers struct {
+	projects       repositories.ProjectRepository
+	auditor        Auditor
+	broadcaster    Broadcaster
+	organizationID uint
+	mapping        GroupMapping
+}
+
+// provision finds or creates the user behind an identity and applies the group mapping, in one transaction.
+// It returns the user, whether it was just created, and ErrSSONotAllowed when no group grants a role.
+func (s *externalUsers) provision(identity *auth.Identity) (*models.User, bool, error) {
+	role, ok := s.mapping.role(identity.Groups)
+	if !ok {
+		return nil, false, ErrSSONotAllowed
+	}
+
+	var user *models.User
+	var removed []*models.ProjectMember
+	var released []*workflowOutcome
+	created := false
+
+	err := s.projects.RunTransaction(func(txRepo repositories.ProjectRepository) error {
+		users := txRepo.Users()
+
+		var err error
+		user, err = users.GetByExternalID(identity.Issuer, identity.Subject)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			username, err := s.freeUsername(users, identity)
+			if err != nil {
+				return err
+			}
+			subject := identity.Subject
+			user = &models.User{
+				OrganizationID:  s.organizationID,
+				Username:        username,
+				Role:            role,
+				Status:          models.UserStatusActive,
+				ExternalIssuer:  identity.Issuer,
+				ExternalSubject: &subject,
+			}
+			if err := users.Create(user); err != nil {
+				return err
+			}
+			created = true
+		case err != nil:
+			return err
+		case user.Role != role:
+			if err := users.Update(user, map[string]interface{}{"role": role}); err != nil {
+				return err
+			}
+		}
+
+		removed, released, err = s.syncMemberships(txRepo, user.ID, identity.Groups)
+		return err
+	})
+	if err != nil {
+		return nil, false, domainError(err)
+	}
+
+	publishOutcomes(s.auditor, s.broadcaster, released)
+	for _, member := range removed {
+		s.publishRemoval(member)
+	}
+	return user, created, nil
+}
+
+// syncMemberships grants the user's mapped memberships and removes the ones granted by a
+// group the user has left, releasing their drawings there. Memberships granted by hand are
+// never changed, even in mapped projects.
+func (s *externalUsers) syncMemberships(txRepo repositories.ProjectRepository, userID uint, groups []string) ([]*models.ProjectMember, []*workflowOutcome, error) {
+	wanted := s.mapping.memberships(groups)
+
+	current, err := txRepo.GetMembershipsBySource(userID, models.MemberSourceGroup)
+	if err != nil {
+		return nil, nil, err
+	}
+	var removed []*models.ProjectMember
+	var released []*workflowOutcome
+	for i := range current {
+		member := &current[i]
+		if slices.ContainsFunc(wanted, func(g GroupProject) bool { return g.ProjectID == member.ProjectID }) {
+			continue
+		}
+		// The last owner goes too: the directory decides, and admins can still manage the project
+		outcomes, err := releaseAssigned(txRepo.Drawings(), member.ProjectID, userID, userID, "Released: assignee left the mapped group")
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := txRepo.RemoveMember(member); err != nil {
+			return nil, nil, err
+		}
+		removed = append(removed, member)
+		released = append(released, outcomes...)
+	}
+
+	for _, mapped := range wanted {
+		member, err := txRepo.GetMember(mapped.ProjectID, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			organizationID, err := txRepo.OrganizationOf(mapped.ProjectID)
+			if err != nil || organizationID != s.organizationID {
+				log.Printf("Group mapping for %q names project %d, which is not in organization %d", mapped.Group, mapped.ProjectID, s.organizationID)
+				continue
+			}
+			err = txRepo.AddMember(&models.ProjectMember{
+				ProjectID: mapped.ProjectID,
+				UserID:    userID,
+				Role:      mapped.Role,
+				IsOwner:   mapped.IsOwner,
+				Source:    models.MemberSourceGroup,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if member.Source != models.MemberSourceGroup {
+			continue
+		}
+		if member.Role != mapped.Role || member.IsOwner != mapped.IsOwner {
+			err := txRepo.UpdateMember(member, map[string]interface{}{"role": mapped.Role, "is_owner": mapped.IsOwner})
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return removed, released, nil
+}
+
+func (s *externalUsers) publishRemoval(member *models.ProjectMember) {
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "project.member_removed",
+		ActorID:      member.UserID,
+		ProjectID:    member.ProjectID,
+		TargetUserID: member.UserID,
+		Details:      map[string]interface{}{"reason": "group_mapping"},
+	})
+	go s.broadcaster.BroadcastEvent(member.ProjectID, "MEMBER_REMOVED", member)
+}
+
+// freeUsername derives a username from the identity, adding a number when it is taken
+func (s *externalUsers) freeUsername(users repositories.UserRepository, identity *auth.Identity) (string, error) {
+	base := identity.Username
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+	base = strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return -1
+	}, base)
+	if len(base) > 26 {
+		base = base[:26]
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	for i := 1; i < 1000; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = base + strconv.Itoa(i)
+		}
+		_, err := users.GetByUsername(s.organizationID, candidate)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no free username for %q", base)
+}
+
+func validRole(role models.UserRole) bool {
+	switch role {
+	case models.RoleAdmin, models.RoleDrafter, models.RoleShiftLead, models.RoleFinalQC:
+		return true
+	}
+	return false
+}
+
+// ParseRoles reads a comma-separated list of roles, such as MFA_REQUIRED_ROLES
+func ParseRoles(list string) ([]models.UserRole, error) {
+	var roles []models.UserRole
+	for _, entry := range splitList(list) {
+		role := models.UserRole(entry)
+		if !validRole(role) {
+			return nil, fmt.Errorf("unknown role %q", entry)
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
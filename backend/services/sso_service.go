@@ -0,0 +1,90 @@
+package services
+
+import (
+	"backend/auth"
+	"backend/models"
+	"backend/repositories"
+	"context"
+	"errors"
+	"log"
+)
+
+var (
+	ErrInvalidSSOState    = &Error{Kind: KindInvalid, Code: "INVALID_SSO_STATE", Message: "Login session expired or was already used, start again"}
+	ErrSSOBrowserMismatch = &Error{Kind: KindInvalid, Code: "SSO_BROWSER_MISMATCH", Message: "This login was started in another browser, start again"}
+	ErrSSOFailed          = &Error{Kind: KindUnauthenticated, Code: "SSO_FAILED", Message: "Single sign-on failed"}
+	ErrSSONotAllowed      = &Error{Kind: KindForbidden, Code: "SSO_NOT_AUTHORIZED", Message: "None of your groups grants access to this system"}
+)
+
+// IdentityProvider is the OpenID Connect flow, implemented by auth.OIDCProvider
+type IdentityProvider interface {
+	AuthCodeURL(ctx context.Context) (string, string, error)
+	Exchange(ctx context.Context, state string, code string, binding string) (*auth.Identity, error)
+}
+
+// SSO logs users in through an OpenID Connect provider, creating them on first login
+type SSO struct {
+	provider IdentityProvider
+	users    *externalUsers
+	tokens   *Tokens
+	auditor  Auditor
+}
+
+// NewSSO puts every SSO user into the given organization
+func NewSSO(provider IdentityProvider, projects repositories.ProjectRepository, tokens *Tokens, auditor Auditor, broadcaster Broadcaster, organizationID uint, mapping GroupMapping) *SSO {
+	return &SSO{
+		provider: provider,
+		users: &externalUsers{
+			projects:       projects,
+			auditor:        auditor,
+			broadcaster:    broadcaster,
+			organizationID: organizationID,
+			mapping:        mapping,
+		},
+		tokens:  tokens,
+		auditor: auditor,
+	}
+}
+
+// Start returns the identity provider URL that begins a login, and the binding value
+// the browser must keep until the callback
+func (s *SSO) Start(ctx context.Context) (string, string, error) {
+	url, binding, err := s.provider.AuthCodeURL(ctx)
+	if err != nil {
+		return "", "", Internal(err)
+	}
+	return url, binding, nil
+}
+
+// Callback completes a login with the code and state the provider sent back
+func (s *SSO) Callback(ctx context.Context, state string, code string, binding string) (*TokenPair, error) {
+	identity, err := s.provider.Exchange(ctx, state, code, binding)
+	if errors.Is(err, auth.ErrOIDCState) {
+		return nil, ErrInvalidSSOState
+	}
+	if errors.Is(err, auth.ErrOIDCBinding) {
+		return nil, ErrSSOBrowserMismatch
+	}
+	if err != nil {
+		log.Printf("SSO login failed: %v", err)
+		return nil, ErrSSOFailed
+	}
+
+	user, created, err := s.users.provision(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "auth.sso_login",
+		ActorID:      user.ID,
+		TargetUserID: user.ID,
+		Details: map[string]interface{}{
+			"issuer":  identity.Issuer,
+			"created": created,
+			"role":    user.Role,
+			"groups":  identity.Groups,
+		},
+	})
+	return s.tokens.Issue(user)
+}
@@ -0,0 +1,414 @@
+package services_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+	"backend/repositories"
+	"backend/services"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const testClientID = "drawings"
+
+// mockIssuer is an OpenID Connect provider serving discovery, JWKS and the token endpoint.
+// Tests authorize a code with authorize, standing in for the user logging in.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]issuedCode
+}
+
+type issuedCode struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &mockIssuer{key: key, codes: map[string]issuedCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                issuer.server.URL,
+			"authorization_endpoint":                issuer.server.URL + "/authorize",
+			"token_endpoint":                        issuer.server.URL + "/token",
+			"jwks_uri":                              issuer.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+// authorize issues code for a login whose authorization URL is authURL, as the provider
+// would after the user signs in
+func (i *mockIssuer) authorize(t *testing.T, authURL string, code string, claims jwt.MapClaims) (state string) {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorization URL has no S256 challenge: %s", authURL)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.codes[code] = issuedCode{challenge: query.Get("code_challenge"), nonce: query.Get("nonce"), claims: claims}
+	return query.Get("state")
+}
+
+func (i *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	i.mu.Lock()
+	issued, ok := i.codes[r.Form.Get("code")]
+	delete(i.codes, r.Form.Get("code"))
+	i.mu.Unlock()
+
+	digest := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(digest[:]) != issued.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   i.server.URL,
+		"aud":   testClientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": issued.nonce,
+	}
+	for name, value := range issued.claims {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(i.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+type nopAuditor struct{}
+
+func (nopAuditor) ProduceAuditLog(models.WorkflowLog)  {}
+func (nopAuditor) ProduceAuditEvent(models.AuditEvent) {}
+
+type nopSessions struct{}
+
+func (nopSessions) TerminateSessions(uint, string) {}
+
+type nopBroadcaster struct{}
+
+func (nopBroadcaster) BroadcastEvent(uint, string, interface{}) {}
+
+type ssoFixture struct {
+	issuer   *mockIssuer
+	sso      *services.SSO
+	db       *gorm.DB
+	projects [3]*models.Project
+}
+
+func newSSOFixture(t *testing.T, mapping services.GroupMapping) *ssoFixture {
+	if err := auth.InitKeys("test-secret-test-secret-test-secret", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Project{}, &models.ProjectMember{}, &models.Drawing{}, &models.WorkflowLog{}, &models.RefreshToken{}); err != nil {
+		t.Fatal(err)
+	}
+	fixture := &ssoFixture{issuer: newMockIssuer(t), db: db}
+	for i := range fixture.projects {
+		fixture.projects[i] = &models.Project{OrganizationID: 1, Name: "project " + string(rune('A'+i))}
+		db.Create(fixture.projects[i])
+	}
+	for i := range mapping.Projects {
+		mapping.Projects[i].ProjectID = fixture.projects[mapping.Projects[i].ProjectID].ID
+	}
+
+	logins := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	provider, err := auth.NewOIDCProvider(context.Background(), auth.OIDCConfig{
+		Issuer:      fixture.issuer.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost:5173/auth/callback",
+	}, logins)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo := repositories.NewUserRepository(db)
+	tokens := services.NewTokens(repositories.NewRefreshTokenRepository(db), userRepo, nopAuditor{}, nopSessions{}, time.Hour)
+	fixture.sso = services.NewSSO(provider, repositories.NewProjectRepository(db), tokens, nopAuditor{}, nopBroadcaster{}, 1, mapping)
+	return fixture
+}
+
+// login runs a full login for the identity in claims and returns the callback's result
+func (f *ssoFixture) login(t *testing.T, code string, claims jwt.MapClaims) (*services.TokenPair, error) {
+	authURL, binding, err := f.sso.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := f.issuer.authorize(t, authURL, code, claims)
+	return f.sso.Callback(context.Background(), state, code, binding)
+}
+
+func (f *ssoFixture) user(t *testing.T, subject string) models.User {
+	var user models.User
+	if err := f.db.Where("external_subject = ?", subject).First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func (f *ssoFixture) memberships(t *testing.T, userID uint) map[uint]models.ProjectMember {
+	var members []models.ProjectMember
+	if err := f.db.Where("user_id = ?", userID).Find(&members).Error; err != nil {
+		t.Fatal(err)
+	}
+	byProject := map[uint]models.ProjectMember{}
+	for _, member := range members {
+		byProject[member.ProjectID] = member
+	}
+	return byProject
+}
+
+// testMapping names projects by their index in ssoFixture.projects; the last one is not mapped
+func testMapping() services.GroupMapping {
+	return services.GroupMapping{
+		Roles: []services.GroupRole{
+			{Group: "qc-admins", Role: models.RoleAdmin},
+			{Group: "qc-leads", Role: models.RoleShiftLead},
+		},
+		Projects: []services.GroupProject{
+			{Group: "qc-leads", ProjectID: 0, Role: models.RoleShiftLead, IsOwner: true},
+			{Group: "drafters", ProjectID: 1, Role: models.RoleDrafter},
+		},
+	}
+}
+
+func TestSSOLoginProvisionsFromGroups(t *testing.T) {
+	f := newSSOFixture(t, testMapping())
+
+	pair, err := f.login(t, "code-1", jwt.MapClaims{
+		"sub":                "alice-sub",
+		"preferred_username": "alice",
+		"groups":             []string{"qc-leads", "drafters"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.AccessToken == "" || pair.RefreshToken == "" {
+		t.Fatalf("login returned no tokens: %+v", pair)
+	}
+
+	user := f.user(t, "alice-sub")
+	if user.Username != "alice" || user.Role != models.RoleShiftLead || user.ExternalIssuer != f.issuer.server.URL {
+		t.Fatalf("unexpected user %+v", user)
+	}
+	members := f.memberships(t, user.ID)
+	if owner := members[f.projects[0].ID]; owner.Role != models.RoleShiftLead || !owner.IsOwner {
+		t.Fatalf("unexpected membership in the leads' project: %+v", owner)
+	}
+	if drafter := members[f.projects[1].ID]; drafter.Role != models.RoleDrafter || drafter.IsOwner || drafter.Source != models.MemberSourceGroup {
+		t.Fatalf("unexpected membership in the drafters' project: %+v", drafter)
+	}
+
+	// The next login follows the groups again, and a group naming the admins wins
+	if _, err := f.login(t, "code-2", jwt.MapClaims{
+		"sub":    "alice-sub",
+		"groups": []string{"drafters", "qc-admins", "qc-leads"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if again := f.user(t, "alice-sub"); again.ID != user.ID || again.Role != models.RoleAdmin {
+		t.Fatalf("unexpected user after second login %+v", again)
+	}
+}
+
+func TestSSOLoginRemovesMembershipsOfLeftGroups(t *testing.T) {
+	f := newSSOFixture(t, testMapping())
+
+	claims := jwt.MapClaims{"sub": "alice-sub", "groups": []string{"qc-leads", "drafters"}}
+	if _, err := f.login(t, "code-1", claims); err != nil {
+		t.Fatal(err)
+	}
+	user := f.user(t, "alice-sub")
+	drawing := &models.Drawing{Title: "D-1", ProjectID: f.projects[1].ID, AuthorID: user.ID, CurrentStage: models.StageDrafting, AssigneeID: &user.ID}
+	f.db.Create(drawing)
+	// Granted by hand, so no login may take it away
+	f.db.Create(&models.ProjectMember{ProjectID: f.projects[2].ID, UserID: user.ID, Role: models.RoleFinalQC, Source: models.MemberSourceManual})
+
+	claims["groups"] = []string{"qc-leads"}
+	if _, err := f.login(t, "code-2", claims); err != nil {
+		t.Fatal(err)
+	}
+	members := f.memberships(t, user.ID)
+	if _, ok := members[f.projects[1].ID]; ok {
+		t.Fatal("membership of the left group was kept")
+	}
+	if _, ok := members[f.projects[0].ID]; !ok {
+		t.Fatal("membership of a current group was removed")
+	}
+	if manual := members[f.projects[2].ID]; manual.Role != models.RoleFinalQC || manual.Source != models.MemberSourceManual {
+		t.Fatalf("hand-granted membership changed: %+v", manual)
+	}
+	var released models.Drawing
+	f.db.First(&released, drawing.ID)
+	if released.AssigneeID != nil || released.CurrentStage != models.StageDrafting {
+		t.Fatalf("drawing in the left project not released: %+v", released)
+	}
+}
+
+func TestSSOLoginKeepsHandGrantedMembershipInMappedProject(t *testing.T) {
+	f := newSSOFixture(t, testMapping())
+
+	claims := jwt.MapClaims{"sub": "alice-sub", "groups": []string{"qc-leads"}}
+	if _, err := f.login(t, "code-1", claims); err != nil {
+		t.Fatal(err)
+	}
+	user := f.user(t, "alice-sub")
+	f.db.Create(&models.ProjectMember{ProjectID: f.projects[1].ID, UserID: user.ID, Role: models.RoleFinalQC, Source: models.MemberSourceManual})
+
+	// Joining and leaving the group mapped to the same project leaves the grant as it was
+	for i, groups := range [][]string{{"qc-leads", "drafters"}, {"qc-leads"}} {
+		claims["groups"] = groups
+		if _, err := f.login(t, fmt.Sprintf("code-%d", i+2), claims); err != nil {
+			t.Fatal(err)
+		}
+		if manual := f.memberships(t, user.ID)[f.projects[1].ID]; manual.Role != models.RoleFinalQC || manual.Source != models.MemberSourceManual {
+			t.Fatalf("hand-granted membership changed: %+v", manual)
+		}
+	}
+}
+
+func TestSSOLoginWithoutMappedGroupIsRefused(t *testing.T) {
+	f := newSSOFixture(t, testMapping())
+
+	_, err := f.login(t, "code-1", jwt.MapClaims{"sub": "bob-sub", "groups": []string{"visitors"}})
+	if err != services.ErrSSONotAllowed {
+		t.Fatalf("got %v, want %v", err, services.ErrSSONotAllowed)
+	}
+	var count int64
+	f.db.Model(&models.User{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("%d users created for a refused login", count)
+	}
+}
+
+func TestSSOStateWorksOnce(t *testing.T) {
+	f := newSSOFixture(t, testMapping())
+
+	authURL, binding, err := f.sso.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.MapClaims{"sub": "alice-sub", "groups": []string{"drafters", "qc-leads"}}
+	state := f.issuer.authorize(t, authURL, "code-1", claims)
+	if _, err := f.sso.Callback(context.Background(), state, "code-1", binding); err != nil {
+		t.Fatal(err)
+	}
+
+	f.issuer.authorize(t, authURL, "code-2", claims)
+	if _, err := f.sso.Callback(context.Background(), state, "code-2", binding); err != services.ErrInvalidSSOState {
+		t.Fatalf("got %v, want %v", err, services.ErrInvalidSSOState)
+	}
+}
+
+func TestSSOCallbackNeedsTheStartingBrowser(t *testing.T) {
+	f := newSSOFixture(t, testMapping())
+
+	authURL, _, err := f.sso.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := f.issuer.authorize(t, authURL, "code-1", jwt.MapClaims{"sub": "alice-sub", "groups": []string{"qc-leads"}})
+	if _, err := f.sso.Callback(context.Background(), state, "code-1", "another-browser"); err != services.ErrSSOBrowserMismatch {
+		t.Fatalf("got %v, want %v", err, services.ErrSSOBrowserMismatch)
+	}
+}
+
+func TestSSOCodeNeedsTheLoginsVerifier(t *testing.T) {
+	f := newSSOFixture(t, testMapping())
+
+	// A code issued for another login's challenge cannot be redeemed with this login's verifier
+	otherURL, _, err := f.sso.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.issuer.authorize(t, otherURL, "stolen-code", jwt.MapClaims{"sub": "alice-sub", "groups": []string{"qc-leads"}})
+
+	authURL, binding, err := f.sso.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _ := url.Parse(authURL)
+	if _, err := f.sso.Callback(context.Background(), state.Query().Get("state"), "stolen-code", binding); err != services.ErrSSOFailed {
+		t.Fatalf("got %v, want %v", err, services.ErrSSOFailed)
+	}
+}
+
+func TestSSOIDTokenNeedsTheLoginsNonce(t *testing.T) {
+	f := newSSOFixture(t, testMapping())
+
+	_, err := f.login(t, "code-1", jwt.MapClaims{"sub": "alice-sub", "groups": []string{"qc-leads"}, "nonce": "replayed"})
+	if err != services.ErrSSOFailed {
+		t.Fatalf("got %v, want %v", err, services.ErrSSOFailed)
+	}
+}
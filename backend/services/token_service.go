@@ -0,0 +1,244 @@
+package services
+
+import (
+	"backend/auth"
+	"backend/models"
+	"backend/repositories"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = &Error{Kind: KindUnauthenticated, Code: "INVALID_REFRESH_TOKEN", Message: "Invalid or expired refresh token"}
+	ErrRefreshTokenReused  = &Error{Kind: KindUnauthenticated, Code: "REFRESH_TOKEN_REUSED", Message: "Refresh token was already used; all sessions from this login have been revoked"}
+	ErrAccountPending      = &Error{Kind: KindForbidden, Code: "ACCOUNT_PENDING_APPROVAL", Message: "Account is waiting for approval by an administrator"}
+	ErrAccountDeactivated  = &Error{Kind: KindUnauthenticated, Code: "ACCOUNT_DEACTIVATED", Message: "Account has been deactivated"}
+)
+
+// TokenPair is what a successful login or refresh hands back to the client
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+	User         *models.User
+}
+
+// SessionTerminator cuts off a user's open requests and streams
+type SessionTerminator interface {
+	TerminateSessions(userID uint, jti string)
+}
+
+// Tokens issues access tokens, rotates the refresh tokens that renew them and revokes both
+type Tokens struct {
+	repo       repositories.RefreshTokenRepository
+	users      repositories.UserRepository
+	auditor    Auditor
+	sessions   SessionTerminator
+	refreshTTL time.Duration
+}
+
+func NewTokens(repo repositories.RefreshTokenRepository, users repositories.UserRepository, auditor Auditor, sessions SessionTerminator, refreshTTL time.Duration) *Tokens {
+	return &Tokens{
+		repo:       repo,
+		users:      users,
+		auditor:    auditor,
+		sessions:   sessions,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// Issue starts a new refresh token family for a user who just logged in
+func (s *Tokens) Issue(user *models.User) (*TokenPair, error) {
+	if err := loginAllowed(user); err != nil {
+		return nil, err
+	}
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	refreshToken, err := s.createRefreshToken(s.repo, user.ID, familyID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+	return s.pair(user, refreshToken)
+}
+
+// Refresh trades a refresh token for a new access token and a new refresh token.
+// Presenting a token that was already used or revoked revokes its whole family,
+// since either the client or an attacker holds a stolen copy.
+func (s *Tokens) Refresh(raw string) (*TokenPair, error) {
+	var reused *models.RefreshToken
+	var user *models.User
+	var refreshToken string
+
+	err := s.repo.RunTransaction(func(txRepo repositories.RefreshTokenRepository) error {
+		token, err := txRepo.GetByHashForUpdate(hashToken(raw))
+		if err != nil {
+			return notFoundOr(err, ErrInvalidRefreshToken)
+		}
+
+		now := time.Now()
+		if token.UsedAt != nil || token.RevokedAt != nil {
+			// Commit the revocation; the caller still gets an error
+			reused = token
+			return txRepo.RevokeFamily(token.FamilyID, now)
+		}
+		if now.After(token.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		user, err = s.users.Get(token.UserID)
+		if err != nil {
+			return notFoundOr(err, ErrInvalidRefreshToken)
+		}
+		if user.Status != models.UserStatusActive {
+			return ErrInvalidRefreshToken
+		}
+		if err := txRepo.MarkUsed(token.ID, now); err != nil {
+			return err
+		}
+		refreshToken, err = s.createRefreshToken(txRepo, token.UserID, token.FamilyID)
+		return err
+	})
+	if err != nil {
+		return nil, domainError(err)
+	}
+
+	if reused != nil {
+		s.auditor.ProduceAuditEvent(models.AuditEvent{
+			Type:         "auth.refresh_reuse",
+			ActorID:      reused.UserID,
+			TargetUserID: reused.UserID,
+			Details:      map[string]interface{}{"family_id": reused.FamilyID},
+		})
+		return nil, ErrRefreshTokenReused
+	}
+	return s.pair(user, refreshToken)
+}
+
+// ActiveUser loads the user behind a session. Tokens outlive a deactivation, so
+// requests check the account on every call and take the current role from it.
+func (s *Tokens) ActiveUser(userID uint) (*models.User, error) {
+	user, err := s.users.Get(userID)
+	if err != nil {
+		return nil, notFoundOr(err, ErrAccountDeactivated)
+	}
+	if user.Status != models.UserStatusActive {
+		return nil, ErrAccountDeactivated
+	}
+	return user, nil
+}
+
+// Logout revokes the caller's access token and, when given, the refresh token family it came with
+func (s *Tokens) Logout(claims *auth.Claims, refreshToken string) error {
+	if refreshToken != "" {
+		err := s.repo.RunTransaction(func(txRepo repositories.RefreshTokenRepository) error {
+			token, err := txRepo.GetByHashForUpdate(hashToken(refreshToken))
+			if err != nil || token.UserID != claims.UserID {
+				return notFoundOr(err, ErrInvalidRefreshToken)
+			}
+			return txRepo.RevokeFamily(token.FamilyID, time.Now())
+		})
+		if err != nil {
+			return domainError(err)
+		}
+	}
+
+	if err := auth.Revocations.RevokeToken(claims.UserID, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return Internal(err)
+	}
+	s.sessions.TerminateSessions(claims.UserID, claims.ID)
+	return nil
+}
+
+// RevokeAll signs a user out everywhere: every access and refresh token issued so far
+// stops working and their open streams are closed. Admins can only target their own organization.
+func (s *Tokens) RevokeAll(userID uint, actorID uint) error {
+	user, err := s.users.Get(userID)
+	if err != nil {
+		return notFoundOr(err, ErrUserNotFound)
+	}
+	actor, err := s.users.Get(actorID)
+	if err != nil {
+		return notFoundOr(err, ErrUserNotFound)
+	}
+	if actor.OrganizationID != user.OrganizationID {
+		return ErrUserNotFound
+	}
+	if err := s.revoke(user.ID); err != nil {
+		return err
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "auth.sessions_revoked",
+		ActorID:      actorID,
+		TargetUserID: user.ID,
+	})
+	return nil
+}
+
+func (s *Tokens) revoke(userID uint) error {
+	now := time.Now()
+	if err := s.repo.RevokeForUser(userID, now); err != nil {
+		return Internal(err)
+	}
+	if err := auth.Revocations.RevokeUser(userID, now); err != nil {
+		return Internal(err)
+	}
+	s.sessions.TerminateSessions(userID, "")
+	return nil
+}
+
+// loginAllowed rejects accounts that may not start a session
+func loginAllowed(user *models.User) error {
+	switch user.Status {
+	case models.UserStatusPending:
+		return ErrAccountPending
+	case models.UserStatusDeactivated:
+		return ErrAccountDeactivated
+	}
+	return nil
+}
+
+func (s *Tokens) createRefreshToken(repo repositories.RefreshTokenRepository, userID uint, familyID string) (string, error) {
+	raw, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	err = repo.Create(&models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	})
+	return raw, err
+}
+
+func (s *Tokens) pair(user *models.User, refreshToken string) (*TokenPair, error) {
+	accessToken, err := auth.GenerateToken(user.ID, user.OrganizationID, string(user.Role))
+	if err != nil {
+		return nil, Internal(err)
+	}
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    auth.AccessTokenTTL,
+		User:         user,
+	}, nil
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"backend/models"
+	"backend/repositories"
+	"log"
+)
+
+const (
+	DefaultUserPageSize = 50
+	MaxUserPageSize     = 200
+)
+
+var (
+	ErrLastAdmin              = &Error{Kind: KindConflict, Code: "LAST_ADMIN", Message: "An organization must keep at least one active admin"}
+	ErrCannotDeactivateSelf   = &Error{Kind: KindConflict, Code: "CANNOT_DEACTIVATE_SELF", Message: "You cannot deactivate your own account"}
+	ErrUserAlreadyDeactivated = &Error{Kind: KindConflict, Code: "USER_ALREADY_DEACTIVATED", Message: "User is already deactivated"}
+	ErrUserNotDeactivated     = &Error{Kind: KindConflict, Code: "USER_NOT_DEACTIVATED", Message: "User is not deactivated"}
+	ErrInvalidRole            = &Error{Kind: KindInvalid, Code: "INVALID_ROLE", Message: "Unknown role"}
+)
+
+// Users lets admins look up the accounts of their organization, change their role and
+// deactivate leavers. Deactivated accounts keep their history but cannot log in.
+type Users struct {
+	repo        repositories.ProjectRepository
+	tokens      *Tokens
+	auditor     Auditor
+	broadcaster Broadcaster
+}
+
+func NewUsers(repo repositories.ProjectRepository, tokens *Tokens, auditor Auditor, broadcaster Broadcaster) *Users {
+	return &Users{
+		repo:        repo,
+		tokens:      tokens,
+		auditor:     auditor,
+		broadcaster: broadcaster,
+	}
+}
+
+// Search returns one page of the organization's users and the total number of matches
+func (s *Users) Search(organizationID uint, filter repositories.UserFilter) ([]models.User, int64, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultUserPageSize
+	}
+	if filter.Limit > MaxUserPageSize {
+		filter.Limit = MaxUserPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	users, total, err := s.repo.Users().Search(organizationID, filter)
+	if err != nil {
+		return nil, 0, Internal(err)
+	}
+	return users, total, nil
+}
+
+// ChangeRole sets the user's organization-wide role. It applies to open sessions at
+// their next request; project roles are left alone.
+func (s *Users) ChangeRole(userID uint, organizationID uint, actorID uint, role models.UserRole) (*models.User, error) {
+	if !validRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	var user *models.User
+	var previous models.UserRole
+	err := s.repo.RunTransaction(func(txRepo repositories.ProjectRepository) error {
+		var err error
+		user, err = organizationUser(txRepo, userID, organizationID)
+		if err != nil {
+			return err
+		}
+		previous = user.Role
+		if previous == role {
+			return nil
+		}
+		if previous == models.RoleAdmin && user.Status == models.UserStatusActive {
+			if err := ensureAnotherAdmin(txRepo, organizationID); err != nil {
+				return err
+			}
+		}
+		return txRepo.Users().Update(user, map[string]interface{}{"role": role})
+	})
+	if err != nil {
+		return nil, domainError(err)
+	}
+
+	if previous != role {
+		s.auditor.ProduceAuditEvent(models.AuditEvent{
+			Type:         "user.role_changed",
+			ActorID:      actorID,
+			TargetUserID: user.ID,
+			Details:      map[string]interface{}{"from": previous, "to": role},
+		})
+	}
+	return user, nil
+}
+
+// Deactivate locks a leaver out: their drawings are released, their sessions and API
+// keys stop working and they can no longer log in.
+func (s *Users) Deactivate(userID uint, organizationID uint, actorID uint) (*models.User, error) {
+	if userID == actorID {
+		return nil, ErrCannotDeactivateSelf
+	}
+
+	var user *models.User
+	var released []*workflowOutcome
+	err := s.repo.RunTransaction(func(txRepo repositories.ProjectRepository) error {
+		var err error
+		user, err = organizationUser(txRepo, userID, organizationID)
+		if err != nil {
+			return err
+		}
+		if user.Status == models.UserStatusDeactivated {
+			return ErrUserAlreadyDeactivated
+		}
+		if user.Role == models.RoleAdmin && user.Status == models.UserStatusActive {
+			if err := ensureAnotherAdmin(txRepo, organizationID); err != nil {
+				return err
+			}
+		}
+
+		released, err = releaseAssigned(txRepo.Drawings(), 0, userID, actorID, "Released: assignee deactivated")
+		if err != nil {
+			return err
+		}
+		return txRepo.Users().Update(user, map[string]interface{}{"status": models.UserStatusDeactivated})
+	})
+	if err != nil {
+		return nil, domainError(err)
+	}
+
+	publishOutcomes(s.auditor, s.broadcaster, released)
+	// Requests already fail on the account status; this also ends open streams
+	if err := s.tokens.revoke(user.ID); err != nil {
+		log.Printf("Failed to revoke sessions of deactivated user %d: %v", user.ID, err)
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "user.deactivated",
+		ActorID:      actorID,
+		TargetUserID: user.ID,
+		Details:      map[string]interface{}{"released_drawings": len(released)},
+	})
+	return user, nil
+}
+
+// Reactivate lets a deactivated user log in again
+func (s *Users) Reactivate(userID uint, organizationID uint, actorID uint) (*models.User, error) {
+	user, err := organizationUser(s.repo, userID, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != models.UserStatusDeactivated {
+		return nil, ErrUserNotDeactivated
+	}
+	if err := s.repo.Users().Update(user, map[string]interface{}{"status": models.UserStatusActive}); err != nil {
+		return nil, Internal(err)
+	}
+
+	s.auditor.ProduceAuditEvent(models.AuditEvent{
+		Type:         "user.reactivated",
+		ActorID:      actorID,
+		TargetUserID: user.ID,
+	})
+	return user, nil
+}
+
+// organizationUser loads a user of the organization; users of other organizations are reported as missing
+func organizationUser(repo repositories.ProjectRepository, userID uint, organizationID uint) (*models.User, error) {
+	user, err := repo.Users().Get(userID)
+	if err != nil || user.OrganizationID != organizationID {
+		return nil, notFoundOr(err, ErrUserNotFound)
+	}
+	return user, nil
+}
+
+func ensureAnotherAdmin(txRepo repositories.ProjectRepository, organizationID uint) error {
+	admins, err := txRepo.Users().CountByRoleForUpdate(organizationID, models.RoleAdmin, models.UserStatusActive)
+	if err != nil {
+		return Internal(err)
+	}
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
@@ -1,13 +1,21 @@
 import React, { useEffect, useState } from 'react';
 import { Layers } from 'lucide-react';
+import { projectService } from '../services/projectService';
 
 const ProjectSelector = ({ currentProject, onSelect }) => {
-    // In a real app, we'd fetch these from /api/v1/projects
-    // For now, we'll hardcode the ones we seeded.
-    const projects = [
-        { id: 1, name: "Aerodynamics Suite" },
-        { id: 2, name: "Power Unit" }
-    ];
+    const [projects, setProjects] = useState([]);
+
+    useEffect(() => {
+        projectService.getMine()
+            .then((data) => {
+                setProjects(data);
+                // Fall back to the first project the user belongs to
+                if (data.length > 0 && !data.some(p => p.id === currentProject)) {
+                    onSelect(data[0].id);
+                }
+            })
+            .catch(() => setProjects([]));
+    }, []);
 
     return (
         <div className="flex items-center space-x-4 bg-gray-800 p-2 rounded-lg border border-gray-700">
@@ -1,5 +1,6 @@
 import React, { createContext, useState, useContext, useEffect } from 'react';
 import { useNavigate } from 'react-router-dom';
+import api from '../services/apiConfig';
 
 const AuthContext = createContext();
 
@@ -20,14 +21,22 @@ export const AuthProvider = ({ children }) => {
 
     const login = (data) => {
         localStorage.setItem('token', data.token);
+        localStorage.setItem('refresh_token', data.refresh_token);
         localStorage.setItem('role', data.role);
         localStorage.setItem('user_id', data.user_id);
         setUser({ token: data.token, role: data.role, userID: data.user_id });
         navigate('/dashboard');
     };
 
-    const logout = () => {
+    const logout = async () => {
+        // Revoke the tokens server-side; sign out locally even if that fails
+        try {
+            await api.post('/auth/logout', { refresh_token: localStorage.getItem('refresh_token') || '' });
+        } catch {
+            // Ignore
+        }
         localStorage.removeItem('token');
+        localStorage.removeItem('refresh_token');
         localStorage.removeItem('role');
         localStorage.removeItem('user_id');
         setUser(null);
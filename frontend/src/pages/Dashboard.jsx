@@ -1,5 +1,6 @@
 import React, { useEffect, useState, useRef } from 'react';
 import { drawingService } from '../services/drawingService';
+import { projectService } from '../services/projectService';
 import { useAuth } from '../context/AuthContext';
 import { Clock, UserPlus } from 'lucide-react';
 import TaskCard from '../components/TaskCard';
@@ -36,23 +37,41 @@ const Dashboard = () => {
             eventSourceRef.current.close();
         }
 
-        const token = localStorage.getItem('token');
-        const eventSource = new EventSource(`/api/v1/events?project_id=${currentProjectID}&token=${token}`);
-        eventSourceRef.current = eventSource;
-
-        eventSource.onmessage = (event) => {
-            const data = JSON.parse(event.data);
-            // Optimization: We could merge data locally, but fetching ensures consistency
-            fetchDrawings(currentProjectID);
-        };
-
-        eventSource.onerror = (err) => {
-            // console.error("SSE Error:", err); 
-            // Silent retry logic is handled by browser, but we close on fatal
-            // eventSource.close();
+        let reconnectTimer = null;
+        let cancelled = false;
+        const connect = async () => {
+            if (cancelled) return;
+            let ticket;
+            try {
+                // The API client refreshes an expired access token on its own
+                ticket = await projectService.getEventTicket(currentProjectID);
+            } catch {
+                reconnectTimer = setTimeout(connect, 5000);
+                return;
+            }
+            if (cancelled) return;
+            const eventSource = new EventSource(`/api/v1/events?project_id=${currentProjectID}&ticket=${encodeURIComponent(ticket)}`);
+            eventSourceRef.current = eventSource;
+
+            eventSource.onmessage = (event) => {
+                const data = JSON.parse(event.data);
+                // Optimization: We could merge data locally, but fetching ensures consistency
+                fetchDrawings(currentProjectID);
+            };
+
+            eventSource.onerror = () => {
+                // Tickets are single-use, so the browser's own retry would be refused;
+                // reconnect with a new ticket instead
+                eventSource.close();
+                clearTimeout(reconnectTimer);
+                reconnectTimer = setTimeout(connect, 1000);
+            };
         };
+        connect();
 
         return () => {
+            cancelled = true;
+            clearTimeout(reconnectTimer);
             if (eventSourceRef.current) {
                 eventSourceRef.current.close();
             }
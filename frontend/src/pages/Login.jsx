@@ -1,14 +1,20 @@
 import React, { useState } from 'react';
 import { useAuth } from '../context/AuthContext';
 import { authService } from '../services/authService';
-import { LogIn } from 'lucide-react';
+import { LogIn, ShieldCheck } from 'lucide-react';
 import { Link } from 'react-router-dom';
 
 const Login = () => {
+    const [organization, setOrganization] = useState('');
     const [username, setUsername] = useState('');
     const [password, setPassword] = useState('');
     const [error, setError] = useState('');
     const [loading, setLoading] = useState(false);
+    // Second step: the pending MFA login, a new authenticator to set up, and recovery codes to show once
+    const [mfa, setMfa] = useState(null);
+    const [enrollment, setEnrollment] = useState(null);
+    const [code, setCode] = useState('');
+    const [recovery, setRecovery] = useState(null);
     const { login } = useAuth();
 
     const handleSubmit = async (e) => {
@@ -18,7 +24,14 @@ const Login = () => {
         setLoading(true);
         setError('');
         try {
-            const data = await authService.login(username, password);
+            const data = await authService.login(username, password, organization || undefined);
+            if (data.mfa_required) {
+                setMfa(data);
+                if (data.enrollment_required) {
+                    setEnrollment(await authService.enrollMfa(data.mfa_token));
+                }
+                return;
+            }
             login(data);
         } catch (err) {
             setError(err.response?.data?.error || 'Login failed');
@@ -27,6 +40,103 @@ const Login = () => {
         }
     };
 
+    const handleMfa = async (e) => {
+        e.preventDefault();
+        if (loading) return;
+
+        setLoading(true);
+        setError('');
+        try {
+            const data = await authService.completeMfa(mfa.mfa_token, code);
+            if (data.recovery_codes) {
+                setRecovery(data);
+                return;
+            }
+            login(data);
+        } catch (err) {
+            setError(err.response?.data?.error || 'Verification failed');
+            if (err.response?.data?.code === 'INVALID_MFA_CHALLENGE') {
+                setMfa(null);
+                setEnrollment(null);
+            }
+        } finally {
+            setLoading(false);
+            setCode('');
+        }
+    };
+
+    if (recovery) {
+        return (
+            <div className="min-h-screen flex items-center justify-center bg-gray-900 text-white">
+                <div className="bg-gray-800 p-8 rounded-xl shadow-2xl w-full max-w-md border border-gray-700">
+                    <h1 className="text-2xl font-bold mb-4">Save Your Recovery Codes</h1>
+                    <p className="text-sm text-gray-400 mb-4">
+                        Each code signs you in once if you lose your authenticator. They are not shown again.
+                    </p>
+                    <div className="grid grid-cols-2 gap-2 font-mono text-center bg-gray-900 p-4 rounded-lg mb-6">
+                        {recovery.recovery_codes.map((c) => <span key={c}>{c}</span>)}
+                    </div>
+                    <button
+                        type="button"
+                        onClick={() => login(recovery)}
+                        className="w-full font-bold py-3 px-4 rounded-lg bg-blue-600 hover:bg-blue-700 text-white transition-all shadow-lg"
+                    >
+                        I Saved Them, Continue
+                    </button>
+                </div>
+            </div>
+        );
+    }
+
+    if (mfa) {
+        return (
+            <div className="min-h-screen flex items-center justify-center bg-gray-900 text-white">
+                <div className="bg-gray-800 p-8 rounded-xl shadow-2xl w-full max-w-md border border-gray-700">
+                    <div className="flex items-center justify-center mb-6">
+                        <ShieldCheck className="w-10 h-10 text-blue-500 mr-2" />
+                        <h1 className="text-2xl font-bold">Two-Factor Authentication</h1>
+                    </div>
+                    {enrollment && (
+                        <div className="mb-6 text-center">
+                            <p className="text-sm text-gray-400 mb-4">
+                                Your role requires two-factor authentication. Scan this code with an authenticator app, then enter the code it shows.
+                            </p>
+                            <img src={enrollment.qr_code} alt="Authenticator QR code" className="mx-auto mb-2 bg-white p-2 rounded-lg w-48 h-48" />
+                            <p className="text-xs text-gray-500 font-mono break-all">{enrollment.secret}</p>
+                        </div>
+                    )}
+                    <form onSubmit={handleMfa} className="space-y-6">
+                        <div>
+                            <label className="block text-sm font-medium mb-1">
+                                {enrollment ? 'Authenticator Code' : 'Authenticator or Recovery Code'}
+                            </label>
+                            <input
+                                type="text"
+                                autoComplete="one-time-code"
+                                className="w-full bg-gray-700 border border-gray-600 rounded-lg px-4 py-2 focus:ring-2 focus:ring-blue-500 outline-none font-mono tracking-widest"
+                                value={code}
+                                onChange={(e) => setCode(e.target.value)}
+                                autoFocus
+                                required
+                            />
+                        </div>
+                        {error && <p className="text-red-400 text-sm">{error}</p>}
+                        <button
+                            type="submit"
+                            disabled={loading}
+                            className={`w-full font-bold py-3 px-4 rounded-lg transition-all shadow-lg ${loading
+                                ? 'bg-gray-700 cursor-not-allowed text-gray-400'
+                                : 'bg-blue-600 hover:bg-blue-700 text-white'
+                                }`}
+                        >
+                            {loading ? 'Verifying...' : 'Verify'}
+                        </button>
+                    </form>
+                </div>
+            </div>
+        );
+    }
+
     return (
         <div className="min-h-screen flex items-center justify-center bg-gray-900 text-white">
             <div className="bg-gray-800 p-8 rounded-xl shadow-2xl w-full max-w-md border border-gray-700">
@@ -35,6 +145,16 @@ const Login = () => {
                     <h1 className="text-3xl font-bold">QC System</h1>
                 </div>
                 <form onSubmit={handleSubmit} className="space-y-6">
+                    <div>
+                        <label className="block text-sm font-medium mb-1">Organization</label>
+                        <input
+                            type="text"
+                            className="w-full bg-gray-700 border border-gray-600 rounded-lg px-4 py-2 focus:ring-2 focus:ring-blue-500 outline-none"
+                            value={organization}
+                            onChange={(e) => setOrganization(e.target.value)}
+                            placeholder="default"
+                        />
+                    </div>
                     <div>
                         <label className="block text-sm font-medium mb-1">Username</label>
                         <input
@@ -67,7 +187,19 @@ const Login = () => {
                         {loading ? 'Signing In...' : 'Sign In'}
                     </button>
                 </form>
-                <div className="mt-8 text-center text-gray-400">
+                <button
+                    type="button"
+                    onClick={authService.startSso}
+                    className="w-full mt-4 font-bold py-3 px-4 rounded-lg border border-gray-600 hover:bg-gray-700 transition-all"
+                >
+                    Sign In with SSO
+                </button>
+                <div className="mt-8 text-center text-gray-400 space-y-2">
+                    <p className="text-sm">
+                        <Link to="/reset-password" className="text-blue-400 hover:text-blue-300 transition-colors">
+                            Forgot password?
+                        </Link>
+                    </p>
                     <p className="text-sm">
                         Don't have an account?{' '}
                         <Link to="/register" className="text-blue-400 hover:text-blue-300 font-bold transition-colors">
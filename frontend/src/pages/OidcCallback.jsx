@@ -0,0 +1,47 @@
+import React, { useEffect, useRef, useState } from 'react';
+import { useSearchParams, Link } from 'react-router-dom';
+import { useAuth } from '../context/AuthContext';
+import { authService } from '../services/authService';
+
+// The identity provider redirects here with ?code=&state=; the backend finishes the login
+const OidcCallback = () => {
+    const [searchParams] = useSearchParams();
+    const [error, setError] = useState('');
+    const { login } = useAuth();
+    const started = useRef(false);
+
+    useEffect(() => {
+        // The state is single-use, so never send it twice
+        if (started.current) return;
+        started.current = true;
+
+        const providerError = searchParams.get('error');
+        if (providerError) {
+            setError(searchParams.get('error_description') || providerError);
+            return;
+        }
+
+        authService.completeSso(searchParams.get('code'), searchParams.get('state'))
+            .then(login)
+            .catch(err => setError(err.response?.data?.error || 'Single sign-on failed'));
+    }, [searchParams, login]);
+
+    return (
+        <div className="min-h-screen flex items-center justify-center bg-gray-900 text-white">
+            <div className="bg-gray-800 p-8 rounded-xl shadow-2xl w-full max-w-md border border-gray-700 text-center">
+                {error ? (
+                    <>
+                        <p className="text-red-400 text-sm mb-6">{error}</p>
+                        <Link to="/login" className="text-blue-400 hover:text-blue-300 font-bold transition-colors">
+                            Back to Sign In
+                        </Link>
+                    </>
+                ) : (
+                    <p className="text-gray-400">Signing you in...</p>
+                )}
+            </div>
+        </div>
+    );
+};
+
+export default OidcCallback;
@@ -1,13 +1,17 @@
 import React, { useState } from 'react';
-import { useNavigate, Link } from 'react-router-dom';
+import { useNavigate, useSearchParams, Link } from 'react-router-dom';
 import { authService } from '../services/authService';
 import { UserPlus } from 'lucide-react';
 
 const Register = () => {
+    const [searchParams] = useSearchParams();
+    // Invitation links carry the token as ?invite=
     const [formData, setFormData] = useState({
         username: '',
+        email: '',
         password: '',
-        role: 'drafter'
+        organization: '',
+        invitation_token: searchParams.get('invite') || ''
     });
     const [error, setError] = useState('');
     const [success, setSuccess] = useState('');
@@ -26,9 +30,17 @@ const Register = () => {
         setError('');
         setSuccess('');
         try {
-            await authService.register(formData);
-            setSuccess('Registration successful! Redirecting to login...');
-            setTimeout(() => navigate('/login'), 2000);
+            const data = await authService.register({
+                username: formData.username,
+                email: formData.email || undefined,
+                password: formData.password,
+                organization: formData.organization || undefined,
+                invitation_token: formData.invitation_token || undefined
+            });
+            setSuccess(data.status === 'pending'
+                ? 'Registration received. An administrator has to approve your account before you can sign in.'
+                : 'Registration successful! Redirecting to login...');
+            setTimeout(() => navigate('/login'), data.status === 'pending' ? 5000 : 2000);
         } catch (err) {
             setError(err.response?.data?.error || 'Registration failed');
         } finally {
@@ -36,13 +48,6 @@ const Register = () => {
         }
     };
 
-    const roles = [
-        { value: 'admin', label: 'Admin' },
-        { value: 'drafter', label: 'Drafter' },
-        { value: 'shift_lead', label: 'Shift Lead' },
-        { value: 'final_qc', label: 'Final QC' }
-    ];
-
     return (
         <div className="min-h-screen flex items-center justify-center bg-gray-950 text-white">
             <div className="bg-gray-900 p-8 rounded-xl shadow-2xl w-full max-w-md border border-gray-800">
@@ -67,6 +72,18 @@ const Register = () => {
                         />
                     </div>
 
+                    <div>
+                        <label className="block text-sm font-semibold text-gray-400 mb-1.5 ml-1">Email</label>
+                        <input
+                            type="email"
+                            name="email"
+                            className="w-full bg-gray-800 border border-gray-700 rounded-xl px-4 py-3 focus:ring-2 focus:ring-blue-500 focus:border-transparent outline-none transition-all placeholder-gray-500"
+                            placeholder="Optional - needed to reset a forgotten password"
+                            value={formData.email}
+                            onChange={handleChange}
+                        />
+                    </div>
+
                     <div>
                         <label className="block text-sm font-semibold text-gray-400 mb-1.5 ml-1">Password</label>
                         <input
@@ -81,22 +98,31 @@ const Register = () => {
                     </div>
 
                     <div>
-                        <label className="block text-sm font-semibold text-gray-400 mb-1.5 ml-1">System Role</label>
-                        <select
-                            name="role"
-                            className="w-full bg-gray-800 border border-gray-700 rounded-xl px-4 py-3 focus:ring-2 focus:ring-blue-500 outline-none cursor-pointer appearance-none"
-                            value={formData.role}
+                        <label className="block text-sm font-semibold text-gray-400 mb-1.5 ml-1">Invitation Code</label>
+                        <input
+                            type="text"
+                            name="invitation_token"
+                            className="w-full bg-gray-800 border border-gray-700 rounded-xl px-4 py-3 focus:ring-2 focus:ring-blue-500 focus:border-transparent outline-none transition-all placeholder-gray-500"
+                            placeholder="Optional - sets your role and projects"
+                            value={formData.invitation_token}
                             onChange={handleChange}
-                            required
-                        >
-                            {roles.map(r => (
-                                <option key={r.value} value={r.value} className="bg-gray-900">
-                                    {r.label}
-                                </option>
-                            ))}
-                        </select>
+                        />
                     </div>
 
+                    {!formData.invitation_token && (
+                        <div>
+                            <label className="block text-sm font-semibold text-gray-400 mb-1.5 ml-1">Organization</label>
+                            <input
+                                type="text"
+                                name="organization"
+                                className="w-full bg-gray-800 border border-gray-700 rounded-xl px-4 py-3 focus:ring-2 focus:ring-blue-500 focus:border-transparent outline-none transition-all placeholder-gray-500"
+                                placeholder="Leave empty for the default organization"
+                                value={formData.organization}
+                                onChange={handleChange}
+                            />
+                        </div>
+                    )}
+
                     {error && (
                         <div className="bg-red-900/20 border border-red-500/50 text-red-400 text-sm p-3 rounded-lg flex items-center">
                             <span className="mr-2 italic">⚠️</span> {error}
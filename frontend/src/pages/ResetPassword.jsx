@@ -0,0 +1,104 @@
+import React, { useState } from 'react';
+import { useNavigate, useSearchParams, Link } from 'react-router-dom';
+import { authService } from '../services/authService';
+import { KeyRound } from 'lucide-react';
+
+// Without ?token= this asks for a reset link; with it, it sets the new password
+const ResetPassword = () => {
+    const [searchParams] = useSearchParams();
+    const token = searchParams.get('token');
+    const [organization, setOrganization] = useState('');
+    const [username, setUsername] = useState('');
+    const [password, setPassword] = useState('');
+    const [confirm, setConfirm] = useState('');
+    const [error, setError] = useState('');
+    const [message, setMessage] = useState('');
+    const [loading, setLoading] = useState(false);
+    const navigate = useNavigate();
+
+    const handleSubmit = async (e) => {
+        e.preventDefault();
+        if (loading || message) return;
+
+        setError('');
+        if (token && password !== confirm) {
+            setError('Passwords do not match');
+            return;
+        }
+
+        setLoading(true);
+        try {
+            if (token) {
+                await authService.resetPassword(token, password);
+                setMessage('Password changed. Redirecting to login...');
+                setTimeout(() => navigate('/login'), 2000);
+            } else {
+                const data = await authService.forgotPassword(username, organization || undefined);
+                setMessage(data.message);
+            }
+        } catch (err) {
+            setError(err.response?.data?.error || 'Request failed');
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    const inputClass = 'w-full bg-gray-700 border border-gray-600 rounded-lg px-4 py-2 focus:ring-2 focus:ring-blue-500 outline-none';
+
+    return (
+        <div className="min-h-screen flex items-center justify-center bg-gray-900 text-white">
+            <div className="bg-gray-800 p-8 rounded-xl shadow-2xl w-full max-w-md border border-gray-700">
+                <div className="flex items-center justify-center mb-8">
+                    <KeyRound className="w-10 h-10 text-blue-500 mr-2" />
+                    <h1 className="text-2xl font-bold">{token ? 'Choose a New Password' : 'Reset Password'}</h1>
+                </div>
+                <form onSubmit={handleSubmit} className="space-y-6">
+                    {token ? (
+                        <>
+                            <div>
+                                <label className="block text-sm font-medium mb-1">New Password</label>
+                                <input type="password" className={inputClass} value={password} onChange={(e) => setPassword(e.target.value)} required />
+                            </div>
+                            <div>
+                                <label className="block text-sm font-medium mb-1">Confirm Password</label>
+                                <input type="password" className={inputClass} value={confirm} onChange={(e) => setConfirm(e.target.value)} required />
+                            </div>
+                        </>
+                    ) : (
+                        <>
+                            <div>
+                                <label className="block text-sm font-medium mb-1">Organization</label>
+                                <input type="text" className={inputClass} value={organization} onChange={(e) => setOrganization(e.target.value)} placeholder="default" />
+                            </div>
+                            <div>
+                                <label className="block text-sm font-medium mb-1">Username</label>
+                                <input type="text" className={inputClass} value={username} onChange={(e) => setUsername(e.target.value)} required />
+                            </div>
+                        </>
+                    )}
+                    {error && <p className="text-red-400 text-sm">{error}</p>}
+                    {message && <p className="text-green-400 text-sm">{message}</p>}
+                    <button
+                        type="submit"
+                        disabled={loading || !!message}
+                        className={`w-full font-bold py-3 px-4 rounded-lg transition-all shadow-lg ${loading || message
+                            ? 'bg-gray-700 cursor-not-allowed text-gray-400'
+                            : 'bg-blue-600 hover:bg-blue-700 text-white'
+                            }`}
+                    >
+                        {loading ? 'Sending...' : token ? 'Set Password' : 'Send Reset Link'}
+                    </button>
+                </form>
+                <div className="mt-8 text-center text-gray-400">
+                    <p className="text-sm">
+                        <Link to="/login" className="text-blue-400 hover:text-blue-300 font-bold transition-colors">
+                            Back to Sign In
+                        </Link>
+                    </p>
+                </div>
+            </div>
+        </div>
+    );
+};
+
+export default ResetPassword;
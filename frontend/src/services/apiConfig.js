@@ -4,6 +4,34 @@ const api = axios.create({
     baseURL: '/api/v1',
 });
 
+const clearSession = () => {
+    localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
+    localStorage.removeItem('role');
+    localStorage.removeItem('user_id');
+};
+
+// Shared so that concurrent 401s trigger a single refresh; refresh tokens are single-use
+let refreshPromise = null;
+
+export const refreshAccessToken = () => {
+    if (!refreshPromise) {
+        const refreshToken = localStorage.getItem('refresh_token');
+        refreshPromise = (refreshToken
+            ? axios.post('/api/v1/auth/refresh', { refresh_token: refreshToken })
+            : Promise.reject(new Error('No refresh token')))
+            .then((response) => {
+                localStorage.setItem('token', response.data.token);
+                localStorage.setItem('refresh_token', response.data.refresh_token);
+                return response.data.token;
+            })
+            .finally(() => {
+                refreshPromise = null;
+            });
+    }
+    return refreshPromise;
+};
+
 api.interceptors.request.use((config) => {
     const token = localStorage.getItem('token');
     if (token) {
@@ -14,13 +42,22 @@ api.interceptors.request.use((config) => {
 
 api.interceptors.response.use(
     (response) => response,
-    (error) => {
+    async (error) => {
+        const original = error.config;
         if (error.response && error.response.status === 401) {
             // Only redirect if NOT on the login page to avoid clearing the error state
-            if (!error.config.url.includes('/login')) {
-                localStorage.removeItem('token');
-                localStorage.removeItem('role');
-                localStorage.removeItem('user_id');
+            if (!original.url.includes('/login')) {
+                if (!original._retried) {
+                    original._retried = true;
+                    try {
+                        const token = await refreshAccessToken();
+                        original.headers.Authorization = `Bearer ${token}`;
+                        return api(original);
+                    } catch {
+                        // Fall through to a fresh login
+                    }
+                }
+                clearSession();
                 window.location.href = '/login';
             }
         }
@@ -1,8 +1,31 @@
 import api from './apiConfig';
 
 export const authService = {
-    login: async (username, password) => {
-        const response = await api.post('/login', { username, password });
+    // organization is optional; the backend falls back to the default organization
+    login: async (username, password, organization) => {
+        const response = await api.post('/login', { organization, username, password });
+        return response.data;
+    },
+
+    // Second login step for users with two-factor authentication
+    completeMfa: async (mfaToken, code) => {
+        const response = await api.post('/login/mfa', { mfa_token: mfaToken, code });
+        return response.data;
+    },
+
+    // For roles that require MFA, when the user has no authenticator yet
+    enrollMfa: async (mfaToken) => {
+        const response = await api.post('/login/mfa/enroll', { mfa_token: mfaToken });
+        return response.data;
+    },
+
+    // Single sign-on: the browser goes to the provider, which sends it back to /auth/callback
+    startSso: () => {
+        window.location.href = '/api/v1/auth/oidc/login';
+    },
+
+    completeSso: async (code, state) => {
+        const response = await api.post('/auth/oidc/callback', { code, state });
         return response.data;
     },
 
@@ -11,8 +34,24 @@ export const authService = {
         return response.data;
     },
 
+    // Always succeeds, whether or not the account exists
+    forgotPassword: async (username, organization) => {
+        const response = await api.post('/password/forgot', { organization, username });
+        return response.data;
+    },
+
+    resetPassword: async (token, newPassword) => {
+        await api.post('/password/reset', { token, new_password: newPassword });
+    },
+
+    // Ends every session, the current one included
+    changePassword: async (currentPassword, newPassword) => {
+        await api.post('/me/password', { current_password: currentPassword, new_password: newPassword });
+    },
+
     logout: () => {
         localStorage.removeItem('token');
+        localStorage.removeItem('refresh_token');
         localStorage.removeItem('role');
         localStorage.removeItem('user_id');
         window.location.href = '/login';
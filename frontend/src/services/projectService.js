@@ -0,0 +1,24 @@
+import api from './apiConfig';
+
+export const projectService = {
+    getMine: async () => {
+        const response = await api.get('/projects/mine');
+        return response.data;
+    },
+
+    getAll: async () => {
+        const response = await api.get('/projects');
+        return response.data;
+    },
+
+    getStats: async (projectID) => {
+        const response = await api.get(`/projects/${projectID}/stats`);
+        return response.data;
+    },
+
+    // Single-use and valid for 30 seconds; EventSource cannot send the Authorization header
+    getEventTicket: async (projectID) => {
+        const response = await api.post('/events/ticket', { project_id: projectID });
+        return response.data.ticket;
+    }
+};